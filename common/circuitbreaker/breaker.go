@@ -0,0 +1,157 @@
+// Package circuitbreaker keeps failing channels out of the channel selection for a while,
+// without touching their status in the database.
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+const (
+	StateClosed = iota
+	StateOpen
+	StateHalfOpen
+)
+
+type breaker struct {
+	state               int
+	consecutiveFailures int
+	openedAt            time.Time
+	halfOpenedAt        time.Time
+	probes              int // probe requests in flight while half-open
+}
+
+var (
+	breakers = make(map[string]*breaker)
+	lock     sync.Mutex
+)
+
+func channelKey(channelId int) string {
+	return fmt.Sprintf("channel:%d", channelId)
+}
+
+func channelModelKey(channelId int, modelName string) string {
+	return fmt.Sprintf("channel:%d:model:%s", channelId, modelName)
+}
+
+func keys(channelId int, modelName string) []string {
+	if modelName == "" {
+		return []string{channelKey(channelId)}
+	}
+	return []string{channelKey(channelId), channelModelKey(channelId, modelName)}
+}
+
+func openDuration() time.Duration {
+	return time.Duration(config.CircuitBreakerOpenDuration) * time.Second
+}
+
+// available reports whether the breaker lets a request through, without taking a probe slot
+func (b *breaker) available(now time.Time) bool {
+	switch b.state {
+	case StateOpen:
+		return now.Sub(b.openedAt) >= openDuration()
+	case StateHalfOpen:
+		// probes whose outcome never came back are given up after another open duration
+		return b.probes < config.CircuitBreakerHalfOpenProbes || now.Sub(b.halfOpenedAt) >= openDuration()
+	}
+	return true
+}
+
+// Available reports whether the channel, and the channel for the given model, are not tripped.
+func Available(channelId int, modelName string) bool {
+	if !config.CircuitBreakerEnabled {
+		return true
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	now := time.Now()
+	for _, key := range keys(channelId, modelName) {
+		if b, ok := breakers[key]; ok && !b.available(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Acquire must be called once a channel has been selected, so that an open breaker whose
+// timeout has passed moves to half-open and the request is counted as a probe.
+func Acquire(channelId int, modelName string) {
+	if !config.CircuitBreakerEnabled {
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	now := time.Now()
+	for _, key := range keys(channelId, modelName) {
+		b, ok := breakers[key]
+		if !ok {
+			continue
+		}
+		if b.state == StateOpen && now.Sub(b.openedAt) >= openDuration() {
+			b.state = StateHalfOpen
+			b.halfOpenedAt = now
+			b.probes = 0
+			logger.SysLog(fmt.Sprintf("circuit breaker %s is half-open", key))
+		}
+		if b.state == StateHalfOpen && now.Sub(b.halfOpenedAt) >= openDuration() {
+			b.halfOpenedAt = now
+			b.probes = 0
+		}
+		if b.state == StateHalfOpen {
+			b.probes++
+		}
+	}
+}
+
+// Record feeds the outcome of a relay request to the breakers of the channel and of the channel for the model.
+// A request slower than CIRCUIT_BREAKER_SLOW_THRESHOLD counts as a failure.
+func Record(channelId int, modelName string, success bool, latency time.Duration) {
+	if !config.CircuitBreakerEnabled {
+		return
+	}
+	if success && config.CircuitBreakerSlowThreshold > 0 && latency > time.Duration(config.CircuitBreakerSlowThreshold)*time.Second {
+		success = false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for _, key := range keys(channelId, modelName) {
+		b, ok := breakers[key]
+		if !ok {
+			if success {
+				continue
+			}
+			b = &breaker{}
+			breakers[key] = b
+		}
+		b.record(key, success)
+		if b.state == StateClosed && b.consecutiveFailures == 0 {
+			delete(breakers, key)
+		}
+	}
+}
+
+func (b *breaker) record(key string, success bool) {
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+	if success {
+		if b.state != StateClosed {
+			logger.SysLog(fmt.Sprintf("circuit breaker %s is closed", key))
+		}
+		b.state = StateClosed
+		b.consecutiveFailures = 0
+		return
+	}
+	b.consecutiveFailures++
+	if b.state == StateHalfOpen || b.consecutiveFailures >= config.CircuitBreakerFailureThreshold {
+		if b.state != StateOpen {
+			logger.SysLog(fmt.Sprintf("circuit breaker %s is open after %d consecutive failures", key, b.consecutiveFailures))
+		}
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
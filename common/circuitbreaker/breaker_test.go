@@ -0,0 +1,47 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LeXwDeX/one-api/common/config"
+)
+
+func TestBreaker(t *testing.T) {
+	config.CircuitBreakerEnabled = true
+	config.CircuitBreakerFailureThreshold = 2
+	config.CircuitBreakerHalfOpenProbes = 1
+	config.CircuitBreakerOpenDuration = 0
+
+	Record(1, "gpt-4o", false, time.Second)
+	assert.True(t, Available(1, "gpt-4o"))
+	Record(1, "gpt-4o", false, time.Second)
+	assert.Equal(t, StateOpen, breakers[channelKey(1)].state)
+	// the open duration is zero, so a probe is let through right away
+	assert.True(t, Available(1, "gpt-4o"))
+	Acquire(1, "gpt-4o")
+	assert.Equal(t, StateHalfOpen, breakers[channelModelKey(1, "gpt-4o")].state)
+
+	// a failed probe opens the breaker again
+	Record(1, "gpt-4o", false, time.Second)
+	assert.Equal(t, StateOpen, breakers[channelKey(1)].state)
+
+	Acquire(1, "gpt-4o")
+	Record(1, "gpt-4o", true, time.Second)
+	assert.NotContains(t, breakers, channelKey(1))
+	assert.NotContains(t, breakers, channelModelKey(1, "gpt-4o"))
+}
+
+func TestBreakerOpenDuration(t *testing.T) {
+	config.CircuitBreakerEnabled = true
+	config.CircuitBreakerFailureThreshold = 1
+	config.CircuitBreakerOpenDuration = 60
+
+	Record(2, "", false, time.Second)
+	assert.False(t, Available(2, ""))
+	assert.False(t, Available(2, "gpt-4o"))
+	// other models of another channel are unaffected
+	assert.True(t, Available(3, "gpt-4o"))
+}
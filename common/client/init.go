@@ -6,6 +6,8 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/gorilla/websocket"
+
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/logger"
 )
@@ -14,6 +16,9 @@ var HTTPClient *http.Client
 var ImpatientHTTPClient *http.Client
 var UserContentRequestHTTPClient *http.Client
 
+// WebSocketDialer dials upstream WebSocket APIs, e.g. the Realtime API, through the relay proxy
+var WebSocketDialer *websocket.Dialer
+
 const defaultUserAgent = "Mozilla/5.0 (compatible; One-API/1.0; +https://github.com/LeXwDeX/one-api)"
 
 type userAgentTransport struct {
@@ -55,6 +60,10 @@ func Init() {
 	}
 
 	var relayTransport http.RoundTripper
+	WebSocketDialer = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
 	if config.RelayProxy != "" {
 		logger.SysLog(fmt.Sprintf("using %s as api relay proxy", config.RelayProxy))
 		proxyURL, err := url.Parse(config.RelayProxy)
@@ -64,18 +73,13 @@ func Init() {
 		relayTransport = &http.Transport{
 			Proxy: http.ProxyURL(proxyURL),
 		}
+		WebSocketDialer.Proxy = http.ProxyURL(proxyURL)
 	}
 	relayTransport = ensureTransport(relayTransport)
 
-	if config.RelayTimeout == 0 {
-		HTTPClient = &http.Client{
-			Transport: relayTransport,
-		}
-	} else {
-		HTTPClient = &http.Client{
-			Timeout:   time.Duration(config.RelayTimeout) * time.Second,
-			Transport: relayTransport,
-		}
+	// the relay is bounded by the first token and idle timeouts instead, which leave long streams alone
+	HTTPClient = &http.Client{
+		Transport: relayTransport,
 	}
 
 	ImpatientHTTPClient = &http.Client{
@@ -19,6 +19,8 @@ var Logo = ""
 var TopUpLink = ""
 var ChatLink = ""
 var QuotaPerUnit = 500 * 1000.0 // $0.002 / 1K tokens
+var BatchRatio = 1.0            // applied on top of the other ratios to requests of the Batch API
+var ResponseCacheHitRatio = 1.0 // applied on top of the other ratios to requests answered from the response cache
 var DisplayInCurrencyEnabled = true
 var DisplayTokenStatEnabled = true
 
@@ -112,7 +114,17 @@ var SyncFrequency = env.Int("SYNC_FREQUENCY", 10*60) // unit is second
 var BatchUpdateEnabled = false
 var BatchUpdateInterval = env.Int("BATCH_UPDATE_INTERVAL", 5)
 
-var RelayTimeout = env.Int("RELAY_TIMEOUT", 0) // unit is second
+var RelayTimeout = env.Int("RELAY_TIMEOUT", 0) // unit is second, DEPRECATED: use RELAY_FIRST_TOKEN_TIMEOUT
+
+// the timeouts of the upstream requests, unless set on the channel; unit is second, 0 means no timeout
+var RelayFirstTokenTimeout = env.Int("RELAY_FIRST_TOKEN_TIMEOUT", RelayTimeout)
+var RelayIdleTimeout = env.Int("RELAY_IDLE_TIMEOUT", 0)
+
+var StreamHeartbeatInterval = env.Int("STREAM_HEARTBEAT_INTERVAL", 0) // unit is second, 0 disables the heartbeats
+
+// the directory holding the tokenizer files which are not embedded, e.g. llama3.tiktoken, or take
+// the place of the embedded ones, e.g. cl100k_base.tiktoken
+var TokenizerDataDir = env.String("TOKENIZER_DATA_DIR", "")
 
 var GeminiSafetySetting = env.String("GEMINI_SAFETY_SETTING", "BLOCK_NONE")
 
@@ -150,6 +162,29 @@ var MetricSuccessRateThreshold = env.Float64("METRIC_SUCCESS_RATE_THRESHOLD", 0.
 var MetricSuccessChanSize = env.Int("METRIC_SUCCESS_CHAN_SIZE", 1024)
 var MetricFailChanSize = env.Int("METRIC_FAIL_CHAN_SIZE", 128)
 
+var CircuitBreakerEnabled = env.Bool("CIRCUIT_BREAKER_ENABLED", false)
+var CircuitBreakerFailureThreshold = env.Int("CIRCUIT_BREAKER_FAILURE_THRESHOLD", 5) // consecutive failures
+var CircuitBreakerOpenDuration = env.Int("CIRCUIT_BREAKER_OPEN_DURATION", 30)        // unit is second
+var CircuitBreakerHalfOpenProbes = env.Int("CIRCUIT_BREAKER_HALF_OPEN_PROBES", 1)
+var CircuitBreakerSlowThreshold = env.Int("CIRCUIT_BREAKER_SLOW_THRESHOLD", 0) // unit is second, 0 means latency is ignored
+
+// channels need this many successful requests before latency routing ranks them
+var LatencyRoutingMinSamples = env.Int("LATENCY_ROUTING_MIN_SAMPLES", 5)
+
+// uploaded files are kept in FILE_STORAGE_DIR unless an S3-compatible endpoint is set
+var FileStorageDir = env.String("FILE_STORAGE_DIR", "files")
+var FileStorageS3Endpoint = env.String("FILE_STORAGE_S3_ENDPOINT", "")
+var FileStorageS3Region = env.String("FILE_STORAGE_S3_REGION", "us-east-1")
+var FileStorageS3Bucket = env.String("FILE_STORAGE_S3_BUCKET", "")
+var FileStorageS3AccessKey = env.String("FILE_STORAGE_S3_ACCESS_KEY", "")
+var FileStorageS3SecretKey = env.String("FILE_STORAGE_S3_SECRET_KEY", "")
+var FileMaxSize = env.Int("FILE_MAX_SIZE", 512) // unit is MB
+
+// requests of the Batch API relayed at the same time
+var BatchConcurrency = env.Int("BATCH_CONCURRENCY", 8)
+
+var ResponseCacheTTL = env.Int("RESPONSE_CACHE_TTL", 3600) // unit is second
+
 var InitialRootToken = os.Getenv("INITIAL_ROOT_TOKEN")
 
 var InitialRootAccessToken = os.Getenv("INITIAL_ROOT_ACCESS_TOKEN")
@@ -12,13 +12,25 @@ const (
 	RequestModel      = "request_model"
 	ConvertedRequest  = "converted_request"
 	OriginalModel     = "original_model"
+	FallbackModel     = "fallback_model" // set when the request is served by a model of the fallback chain
+	Batch             = "batch"          // set on requests relayed by the Batch API executor
 	Group             = "group"
 	ModelMapping      = "model_mapping"
 	ChannelName       = "channel_name"
+	ChannelKey        = "channel_key" // fingerprint of the key picked from a multi-key channel
 	TokenId           = "token_id"
 	TokenName         = "token_name"
 	BaseURL           = "base_url"
 	AvailableModels   = "available_models"
 	KeyRequestBody    = "key_request_body"
 	SystemPrompt      = "system_prompt"
+	TokenRpmLimit     = "token_rpm_limit"
+	TokenTpmLimit     = "token_tpm_limit"
+	RateLimitScopes   = "rate_limit_scopes"
+	// TokenResponseCache is set when the token opted in to the response cache
+	TokenResponseCache = "token_response_cache"
+	// ResponseCacheHit is set when the response was replayed from the response cache
+	ResponseCacheHit = "response_cache_hit"
+	// UpstreamFirstByteLatency is the time.Duration until the upstream response headers arrived
+	UpstreamFirstByteLatency = "upstream_first_byte_latency"
 )
@@ -7,15 +7,23 @@ import (
 
 type InMemoryRateLimiter struct {
 	store              map[string]*[]int64
+	counters           map[string]*windowCounter
 	mutex              sync.Mutex
 	expirationDuration time.Duration
 }
 
+// windowCounter counts an amount (requests, tokens) within one fixed window
+type windowCounter struct {
+	windowStart int64
+	count       int64
+}
+
 func (l *InMemoryRateLimiter) Init(expirationDuration time.Duration) {
 	if l.store == nil {
 		l.mutex.Lock()
 		if l.store == nil {
 			l.store = make(map[string]*[]int64)
+			l.counters = make(map[string]*windowCounter)
 			l.expirationDuration = expirationDuration
 			if expirationDuration > 0 {
 				go l.clearExpiredItems()
@@ -37,6 +45,11 @@ func (l *InMemoryRateLimiter) clearExpiredItems() {
 				delete(l.store, key)
 			}
 		}
+		for key, counter := range l.counters {
+			if now-counter.windowStart > int64(l.expirationDuration.Seconds()) {
+				delete(l.counters, key)
+			}
+		}
 		l.mutex.Unlock()
 	}
 }
@@ -68,3 +81,19 @@ func (l *InMemoryRateLimiter) Request(key string, maxRequestNum int, duration in
 	}
 	return true
 }
+
+// Add adds amount to the counter of key in the current fixed window of duration seconds,
+// and returns the resulting count together with the seconds left until the window resets.
+func (l *InMemoryRateLimiter) Add(key string, amount int64, duration int64) (count int64, resetAfter int64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now().Unix()
+	windowStart := now - now%duration
+	counter, ok := l.counters[key]
+	if !ok || counter.windowStart != windowStart {
+		counter = &windowCounter{windowStart: windowStart}
+		l.counters[key] = counter
+	}
+	counter.count += amount
+	return counter.count, windowStart + duration - now
+}
@@ -28,3 +28,37 @@ func ObjectData(c *gin.Context, object interface{}) error {
 func Done(c *gin.Context) {
 	StringData(c, "[DONE]")
 }
+
+// Event is a server-sent event with an optional event name, as used by
+// the Anthropic Messages and OpenAI Responses streaming formats.
+type Event struct {
+	Event string
+	Data  any
+}
+
+func EventData(c *gin.Context, event Event) error {
+	jsonData, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %w", err)
+	}
+	if event.Event != "" {
+		_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Event, jsonData)
+	} else {
+		_, err = fmt.Fprintf(c.Writer, "data: %s\n\n", jsonData)
+	}
+	if err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// Heartbeat writes an SSE comment, which clients ignore but which keeps idle connections open
+// while the upstream is silent.
+func Heartbeat(w gin.ResponseWriter) error {
+	if _, err := w.WriteString(": keepalive\n\n"); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+var modelFallbackLock sync.RWMutex
+
+// ModelFallback holds the fallback chains by group, e.g. {"default": {"gpt-4o": ["gpt-4.1", "claude-3-5-sonnet"]}}
+var ModelFallback = map[string]map[string][]string{}
+
+func ModelFallback2JSONString() string {
+	modelFallbackLock.RLock()
+	defer modelFallbackLock.RUnlock()
+	jsonBytes, err := json.Marshal(ModelFallback)
+	if err != nil {
+		logger.SysError("error marshalling model fallback: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+// UpdateModelFallbackByJSONString replaces the fallback chains, rejecting the chains that list
+// a model twice or fall back to the model itself.
+func UpdateModelFallbackByJSONString(jsonStr string) error {
+	modelFallback := make(map[string]map[string][]string)
+	if err := json.Unmarshal([]byte(jsonStr), &modelFallback); err != nil {
+		return err
+	}
+	for group, chains := range modelFallback {
+		for model, fallbackModels := range chains {
+			seen := map[string]bool{model: true}
+			for _, fallbackModel := range fallbackModels {
+				if seen[fallbackModel] {
+					return fmt.Errorf("fallback chain of model %s in group %s lists %s twice", model, group, fallbackModel)
+				}
+				seen[fallbackModel] = true
+			}
+		}
+	}
+	modelFallbackLock.Lock()
+	defer modelFallbackLock.Unlock()
+	ModelFallback = modelFallback
+	return nil
+}
+
+// GetFallbackModels returns the models to try, in order, once the model has no working channel.
+func GetFallbackModels(group string, model string) []string {
+	modelFallbackLock.RLock()
+	defer modelFallbackLock.RUnlock()
+	return ModelFallback[group][model]
+}
@@ -0,0 +1,25 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFallbackModels(t *testing.T) {
+	original := ModelFallback2JSONString()
+	defer func() { _ = UpdateModelFallbackByJSONString(original) }()
+
+	err := UpdateModelFallbackByJSONString(`{"default": {"gpt-4o": ["gpt-4.1", "claude-3-5-sonnet"]}}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4.1", "claude-3-5-sonnet"}, GetFallbackModels("default", "gpt-4o"))
+	assert.Empty(t, GetFallbackModels("default", "gpt-4.1"))
+	assert.Empty(t, GetFallbackModels("vip", "gpt-4o"))
+
+	assert.Error(t, UpdateModelFallbackByJSONString(`{"default": ["gpt-4.1"]}`))
+	// chains that come back to a model are rejected, the previous chains are kept
+	assert.Error(t, UpdateModelFallbackByJSONString(`{"default": {"gpt-4o": ["gpt-4.1", "gpt-4o"]}}`))
+	assert.Error(t, UpdateModelFallbackByJSONString(`{"default": {"gpt-4o": ["gpt-4.1", "o3", "gpt-4.1"]}}`))
+	assert.Equal(t, []string{"gpt-4.1", "claude-3-5-sonnet"}, GetFallbackModels("default", "gpt-4o"))
+}
@@ -0,0 +1,61 @@
+package routing
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ewmaAlpha is the weight of the newest sample in the moving averages
+const ewmaAlpha = 0.2
+
+type LatencyStats struct {
+	TTFB         time.Duration // time to the upstream response headers
+	Total        time.Duration
+	TTFBSamples  int
+	TotalSamples int
+}
+
+var (
+	latencyStats = make(map[string]*LatencyStats)
+	latencyLock  sync.RWMutex
+)
+
+func latencyKey(channelId int, model string) string {
+	return fmt.Sprintf("%d:%s", channelId, model)
+}
+
+func ewma(average time.Duration, sample time.Duration, samples int) time.Duration {
+	if samples == 0 {
+		return sample
+	}
+	return time.Duration(ewmaAlpha*float64(sample) + (1-ewmaAlpha)*float64(average))
+}
+
+// RecordLatency feeds the latency of a successful relay request, ttfb is 0 when unknown.
+func RecordLatency(channelId int, model string, ttfb time.Duration, total time.Duration) {
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+	key := latencyKey(channelId, model)
+	stats, ok := latencyStats[key]
+	if !ok {
+		stats = &LatencyStats{}
+		latencyStats[key] = stats
+	}
+	if ttfb > 0 {
+		stats.TTFB = ewma(stats.TTFB, ttfb, stats.TTFBSamples)
+		stats.TTFBSamples++
+	}
+	stats.Total = ewma(stats.Total, total, stats.TotalSamples)
+	stats.TotalSamples++
+}
+
+func GetLatency(channelId int, model string) (LatencyStats, bool) {
+	latencyLock.RLock()
+	defer latencyLock.RUnlock()
+	stats, ok := latencyStats[latencyKey(channelId, model)]
+	if !ok {
+		return LatencyStats{}, false
+	}
+	return *stats, true
+}
@@ -0,0 +1,55 @@
+// Package routing holds the channel routing strategies and the latency they are fed with.
+package routing
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+const (
+	// StrategyWeighted picks channels at random, proportionally to their weight
+	StrategyWeighted = "weighted"
+	// StrategyLatency prefers the channels with the lowest observed latency
+	StrategyLatency = "latency"
+)
+
+// Strategy selects the routing strategy by model or by group, a model entry wins over a group entry,
+// e.g. {"groups": {"vip": "latency"}, "models": {"gpt-4o": "latency"}}
+type Strategy struct {
+	Groups map[string]string `json:"groups,omitempty"`
+	Models map[string]string `json:"models,omitempty"`
+}
+
+var strategyLock sync.RWMutex
+var RoutingStrategy = Strategy{}
+
+func RoutingStrategy2JSONString() string {
+	strategyLock.RLock()
+	defer strategyLock.RUnlock()
+	jsonBytes, err := json.Marshal(RoutingStrategy)
+	if err != nil {
+		logger.SysError("error marshalling routing strategy: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateRoutingStrategyByJSONString(jsonStr string) error {
+	strategyLock.Lock()
+	defer strategyLock.Unlock()
+	RoutingStrategy = Strategy{}
+	return json.Unmarshal([]byte(jsonStr), &RoutingStrategy)
+}
+
+func GetStrategy(group string, model string) string {
+	strategyLock.RLock()
+	defer strategyLock.RUnlock()
+	if strategy, ok := RoutingStrategy.Models[model]; ok {
+		return strategy
+	}
+	if strategy, ok := RoutingStrategy.Groups[group]; ok {
+		return strategy
+	}
+	return StrategyWeighted
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocal(t.TempDir())
+
+	err := s.Put(ctx, "1/file-abc", strings.NewReader("hello"), 5)
+	require.NoError(t, err)
+	r, err := s.Get(ctx, "1/file-abc")
+	require.NoError(t, err)
+	content, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+
+	require.NoError(t, s.Delete(ctx, "1/file-abc"))
+	_, err = s.Get(ctx, "1/file-abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.Delete(ctx, "1/file-abc"))
+
+	assert.Error(t, s.Put(ctx, "../escape", strings.NewReader("x"), 1))
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps the content of uploaded files.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	defaultStorage     Storage
+	defaultStorageOnce sync.Once
+)
+
+// Default returns the storage configured by the environment: an S3-compatible bucket when
+// FILE_STORAGE_S3_ENDPOINT is set, otherwise the local FILE_STORAGE_DIR.
+func Default() Storage {
+	defaultStorageOnce.Do(func() {
+		if config.FileStorageS3Endpoint != "" {
+			defaultStorage = NewS3(config.FileStorageS3Endpoint, config.FileStorageS3Region, config.FileStorageS3Bucket,
+				config.FileStorageS3AccessKey, config.FileStorageS3SecretKey)
+			return
+		}
+		defaultStorage = NewLocal(config.FileStorageDir)
+	})
+	return defaultStorage
+}
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/random"
+	"github.com/LeXwDeX/one-api/common/storage"
+	"github.com/LeXwDeX/one-api/middleware"
+	"github.com/LeXwDeX/one-api/model"
+)
+
+const (
+	batchPollInterval         = 10 * time.Second
+	batchCancelCheckInterval  = 5 * time.Second
+	batchCountsUpdateInterval = 2 * time.Second
+	batchRateLimitRetries     = 5
+)
+
+type batchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+type batchRequestLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type batchResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestId  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type batchResultLine struct {
+	Id       string         `json:"id"`
+	CustomId string         `json:"custom_id"`
+	Response *batchResponse `json:"response"`
+	Error    *batchError    `json:"error"`
+}
+
+var batchWakeup = make(chan struct{}, 1)
+
+func notifyBatchExecutor() {
+	select {
+	case batchWakeup <- struct{}{}:
+	default:
+	}
+}
+
+var (
+	batchEngine     *gin.Engine
+	batchEngineOnce sync.Once
+)
+
+// getBatchEngine returns the handler batch requests go through: the same authentication,
+// rate limiting, channel selection, retries and billing as the /v1 relay routes.
+func getBatchEngine() *gin.Engine {
+	batchEngineOnce.Do(func() {
+		batchEngine = gin.New()
+		relayRouter := batchEngine.Group("/v1")
+		relayRouter.Use(middleware.RequestId(), func(c *gin.Context) {
+			c.Set(ctxkey.Batch, true)
+		}, middleware.RelayPanicRecover(), middleware.TokenAuth(), middleware.TokenRateLimit(), middleware.Distribute())
+		for endpoint := range batchEndpoints {
+			relayRouter.POST(strings.TrimPrefix(endpoint, "/v1"), Relay)
+		}
+	})
+	return batchEngine
+}
+
+// RunBatchExecutor runs the queued batches one after another; only the master node runs it.
+func RunBatchExecutor() {
+	failInterruptedBatches()
+	for {
+		batches, err := model.GetBatchesByStatus(model.BatchStatusValidating)
+		if err != nil {
+			logger.SysError("failed to get queued batches: " + err.Error())
+		}
+		for _, batch := range batches {
+			runBatch(batch)
+		}
+		select {
+		case <-batchWakeup:
+		case <-time.After(batchPollInterval):
+		}
+	}
+}
+
+// failInterruptedBatches fails the batches a previous run was executing, as their progress was lost
+// and running them again would bill the finished requests twice.
+func failInterruptedBatches() {
+	for _, status := range []string{model.BatchStatusInProgress, model.BatchStatusFinalizing, model.BatchStatusCancelling} {
+		batches, err := model.GetBatchesByStatus(status)
+		if err != nil {
+			logger.SysError("failed to get interrupted batches: " + err.Error())
+			return
+		}
+		for _, batch := range batches {
+			failBatch(batch, []string{status}, []batchError{{Code: "batch_interrupted", Message: "The batch was interrupted by a restart of the server."}})
+		}
+	}
+}
+
+func failBatch(batch *model.Batch, from []string, errors []batchError) {
+	jsonBytes, _ := json.Marshal(errors)
+	batchErrors := string(jsonBytes)
+	batch.Errors = &batchErrors
+	err := batch.UpdateColumns("errors")
+	if err == nil {
+		_, err = model.UpdateBatchStatus(batch.Id, from, model.BatchStatusFailed, "failed_time", helper.GetTimestamp())
+	}
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to fail batch %s: %s", batch.BatchId, err.Error()))
+	}
+}
+
+// readBatchInput parses the JSONL input file and checks every line against the batch.
+func readBatchInput(ctx context.Context, batch *model.Batch) ([]batchRequestLine, []batchError) {
+	file, err := model.GetFileByFileId(batch.InputFileId, batch.UserId)
+	if err != nil {
+		return nil, []batchError{{Code: "invalid_input_file", Message: "The input file no longer exists."}}
+	}
+	content, err := storage.Default().Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, []batchError{{Code: "invalid_input_file", Message: "The input file could not be read."}}
+	}
+	defer content.Close()
+	return parseBatchInput(content, batch.Endpoint)
+}
+
+func parseBatchInput(content io.Reader, endpoint string) ([]batchRequestLine, []batchError) {
+	var lines []batchRequestLine
+	var errors []batchError
+	customIds := make(map[string]bool)
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var line batchRequestLine
+		err := json.Unmarshal(data, &line)
+		switch {
+		case err != nil:
+			errors = append(errors, batchError{Code: "invalid_json_line", Message: "This line is not parseable as valid JSON.", Line: lineNumber})
+		case line.CustomId == "":
+			errors = append(errors, batchError{Code: "missing_required_parameter", Message: "Missing required parameter: 'custom_id'.", Param: "custom_id", Line: lineNumber})
+		case customIds[line.CustomId]:
+			errors = append(errors, batchError{Code: "duplicate_custom_id", Message: "The custom_id for this request is a duplicate of another request.", Param: "custom_id", Line: lineNumber})
+		case line.Method != http.MethodPost:
+			errors = append(errors, batchError{Code: "invalid_method", Message: "Only POST requests are supported.", Param: "method", Line: lineNumber})
+		case line.Url != endpoint:
+			errors = append(errors, batchError{Code: "mismatched_endpoint", Message: fmt.Sprintf("The provided url does not match the batch endpoint '%s'.", endpoint), Param: "url", Line: lineNumber})
+		default:
+			customIds[line.CustomId] = true
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errors = append(errors, batchError{Code: "invalid_input_file", Message: "The input file could not be read: " + err.Error()})
+	}
+	if len(errors) == 0 && len(lines) == 0 {
+		errors = append(errors, batchError{Code: "empty_file", Message: "The input file contains no requests."})
+	}
+	return lines, errors
+}
+
+// executeBatchLine relays one request of the batch with the key of the token that created it.
+func executeBatchLine(ctx context.Context, key string, line batchRequestLine) *batchResultLine {
+	result := &batchResultLine{
+		Id:       "batch_req_" + random.GetUUID(),
+		CustomId: line.CustomId,
+	}
+	// responses are written to a file, streaming them makes no sense
+	var body map[string]any
+	if err := json.Unmarshal(line.Body, &body); err != nil {
+		result.Error = &batchError{Code: "invalid_request", Message: "The request body is not a JSON object."}
+		return result
+	}
+	delete(body, "stream")
+	delete(body, "stream_options")
+	requestBody, _ := json.Marshal(body)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, line.Url, bytes.NewReader(requestBody))
+		if err != nil {
+			result.Error = &batchError{Code: "invalid_request", Message: err.Error()}
+			return result
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk-"+key)
+		recorder := httptest.NewRecorder()
+		getBatchEngine().ServeHTTP(recorder, req)
+		retryAfter, _ := strconv.Atoi(recorder.Header().Get("Retry-After"))
+		if recorder.Code == http.StatusTooManyRequests && retryAfter > 0 && attempt < batchRateLimitRetries {
+			// rejected by the RPM/TPM limits of the token, wait for the window to move on
+			select {
+			case <-time.After(time.Duration(retryAfter) * time.Second):
+				continue
+			case <-ctx.Done():
+			}
+		}
+		result.Response = &batchResponse{
+			StatusCode: recorder.Code,
+			RequestId:  recorder.Header().Get(helper.RequestIdKey),
+			Body:       recorder.Body.Bytes(),
+		}
+		if !json.Valid(result.Response.Body) {
+			result.Response.Body, _ = json.Marshal(recorder.Body.String())
+		}
+		return result
+	}
+}
+
+func runBatch(batch *model.Batch) {
+	ctx := context.Background()
+	lines, errors := readBatchInput(ctx, batch)
+	if len(errors) > 0 {
+		failBatch(batch, []string{model.BatchStatusValidating}, errors)
+		return
+	}
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil {
+		failBatch(batch, []string{model.BatchStatusValidating}, []batchError{{Code: "invalid_token", Message: "The token that created the batch no longer exists."}})
+		return
+	}
+	batch.TotalCount = len(lines)
+	if err = batch.UpdateColumns("total_count"); err != nil {
+		logger.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+		return
+	}
+	started, err := model.UpdateBatchStatus(batch.Id, []string{model.BatchStatusValidating}, model.BatchStatusInProgress, "in_progress_time", helper.GetTimestamp())
+	if err != nil || !started {
+		// cancelled before it started
+		return
+	}
+	logger.SysLog(fmt.Sprintf("batch %s started with %d requests", batch.BatchId, len(lines)))
+
+	ctx, cancel := context.WithDeadline(ctx, time.Unix(batch.ExpiresTime, 0))
+	defer cancel()
+	var cancelled atomic.Bool
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(batchCancelCheckInterval):
+			}
+			current, err := model.GetBatchById(batch.Id)
+			if err == nil && current.Status == model.BatchStatusCancelling {
+				cancelled.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	var outputLock sync.Mutex
+	var output, errorOutput bytes.Buffer
+	writeResult := func(result *batchResultLine) {
+		jsonBytes, _ := json.Marshal(result)
+		outputLock.Lock()
+		defer outputLock.Unlock()
+		if result.Error == nil && result.Response.StatusCode == http.StatusOK {
+			batch.CompletedCount++
+			output.Write(jsonBytes)
+			output.WriteByte('\n')
+		} else {
+			batch.FailedCount++
+			errorOutput.Write(jsonBytes)
+			errorOutput.WriteByte('\n')
+		}
+	}
+
+	concurrency := config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	jobs := make(chan batchRequestLine)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range jobs {
+				writeResult(executeBatchLine(ctx, token.Key, line))
+			}
+		}()
+	}
+	lastCountsUpdate := time.Now()
+	for i, line := range lines {
+		if ctx.Err() != nil {
+			// requests never sent are reported in the error file
+			code, message := "batch_expired", "This request could not be executed before the completion window expired."
+			if cancelled.Load() {
+				code, message = "batch_cancelled", "This request was not executed as the batch was cancelled."
+			}
+			for _, line := range lines[i:] {
+				writeResult(&batchResultLine{Id: "batch_req_" + random.GetUUID(), CustomId: line.CustomId, Error: &batchError{Code: code, Message: message}})
+			}
+			break
+		}
+		jobs <- line
+		if time.Since(lastCountsUpdate) > batchCountsUpdateInterval {
+			lastCountsUpdate = time.Now()
+			outputLock.Lock()
+			err = batch.UpdateColumns("completed_count", "failed_count")
+			outputLock.Unlock()
+			if err != nil {
+				logger.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	expired := !cancelled.Load() && ctx.Err() == context.DeadlineExceeded
+	cancel()
+
+	finalizeBatch(batch, cancelled.Load(), expired, &output, &errorOutput)
+}
+
+// finalizeBatch stores the output and error files and moves the batch to its final status.
+func finalizeBatch(batch *model.Batch, cancelled bool, expired bool, output *bytes.Buffer, errorOutput *bytes.Buffer) {
+	ctx := context.Background()
+	from := []string{model.BatchStatusCancelling}
+	if !cancelled {
+		_, _ = model.UpdateBatchStatus(batch.Id, []string{model.BatchStatusInProgress}, model.BatchStatusFinalizing, "finalizing_time", helper.GetTimestamp())
+		from = []string{model.BatchStatusFinalizing}
+	}
+	createOutputFile := func(name string, content *bytes.Buffer) string {
+		if content.Len() == 0 {
+			return ""
+		}
+		file := &model.File{
+			UserId:      batch.UserId,
+			TokenId:     batch.TokenId,
+			Filename:    fmt.Sprintf("%s_%s.jsonl", batch.BatchId, name),
+			Purpose:     "batch_output",
+			Bytes:       int64(content.Len()),
+			ContentType: "application/jsonl",
+		}
+		err := CreateFile(ctx, file, content)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to create %s file of batch %s: %s", name, batch.BatchId, err.Error()))
+			return ""
+		}
+		return file.FileId
+	}
+	batch.OutputFileId = createOutputFile("output", output)
+	batch.ErrorFileId = createOutputFile("error", errorOutput)
+	err := batch.UpdateColumns("output_file_id", "error_file_id", "completed_count", "failed_count")
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+	}
+	status, timeColumn := model.BatchStatusCompleted, "completed_time"
+	switch {
+	case cancelled:
+		status, timeColumn = model.BatchStatusCancelled, "cancelled_time"
+	case expired:
+		status, timeColumn = model.BatchStatusExpired, "expired_time"
+	}
+	done, err := model.UpdateBatchStatus(batch.Id, from, status, timeColumn, helper.GetTimestamp())
+	if err == nil && !done {
+		// cancelled once every request had run, nothing is left to cancel
+		_, err = model.UpdateBatchStatus(batch.Id, []string{model.BatchStatusCancelling}, model.BatchStatusCancelled, "cancelled_time", helper.GetTimestamp())
+	}
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to finalize batch %s: %s", batch.BatchId, err.Error()))
+	}
+	logger.SysLog(fmt.Sprintf("batch %s finished: %d completed, %d failed", batch.BatchId, batch.CompletedCount, batch.FailedCount))
+}
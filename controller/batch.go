@@ -0,0 +1,237 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/random"
+	"github.com/LeXwDeX/one-api/model"
+)
+
+// https://platform.openai.com/docs/api-reference/batch
+
+var batchEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+}
+
+type batchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type batchObject struct {
+	Id               string             `json:"id"`
+	Object           string             `json:"object"`
+	Endpoint         string             `json:"endpoint"`
+	Errors           any                `json:"errors"`
+	InputFileId      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileId     *string            `json:"output_file_id"`
+	ErrorFileId      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	FinalizingAt     *int64             `json:"finalizing_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	ExpiredAt        *int64             `json:"expired_at"`
+	CancellingAt     *int64             `json:"cancelling_at"`
+	CancelledAt      *int64             `json:"cancelled_at"`
+	RequestCounts    batchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+func optionalTimestamp(timestamp int64) *int64 {
+	if timestamp == 0 {
+		return nil
+	}
+	return &timestamp
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func toBatchObject(batch *model.Batch) batchObject {
+	object := batchObject{
+		Id:               batch.BatchId,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileId:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		OutputFileId:     optionalString(batch.OutputFileId),
+		ErrorFileId:      optionalString(batch.ErrorFileId),
+		CreatedAt:        batch.CreatedTime,
+		InProgressAt:     optionalTimestamp(batch.InProgressTime),
+		ExpiresAt:        optionalTimestamp(batch.ExpiresTime),
+		FinalizingAt:     optionalTimestamp(batch.FinalizingTime),
+		CompletedAt:      optionalTimestamp(batch.CompletedTime),
+		FailedAt:         optionalTimestamp(batch.FailedTime),
+		ExpiredAt:        optionalTimestamp(batch.ExpiredTime),
+		CancellingAt:     optionalTimestamp(batch.CancellingTime),
+		CancelledAt:      optionalTimestamp(batch.CancelledTime),
+		RequestCounts: batchRequestCounts{
+			Total:     batch.TotalCount,
+			Completed: batch.CompletedCount,
+			Failed:    batch.FailedCount,
+		},
+	}
+	if batch.Errors != nil {
+		var errors []batchError
+		if json.Unmarshal([]byte(*batch.Errors), &errors) == nil {
+			object.Errors = gin.H{"object": "list", "data": errors}
+		}
+	}
+	if batch.Metadata != nil {
+		_ = json.Unmarshal([]byte(*batch.Metadata), &object.Metadata)
+	}
+	return object
+}
+
+type createBatchRequest struct {
+	InputFileId      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+func CreateBatch(c *gin.Context) {
+	var request createBatchRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, "Invalid request: "+err.Error(), "invalid_request")
+		return
+	}
+	if !batchEndpoints[request.Endpoint] {
+		respondOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid endpoint: '%s'", request.Endpoint), "invalid_endpoint")
+		return
+	}
+	if request.CompletionWindow != "24h" {
+		respondOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid completion_window: '%s', only '24h' is supported", request.CompletionWindow), "invalid_completion_window")
+		return
+	}
+	userId := c.GetInt(ctxkey.Id)
+	inputFile, err := model.GetFileByFileId(request.InputFileId, userId)
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("No such File object: %s", request.InputFileId), "invalid_input_file")
+		return
+	}
+	if inputFile.Purpose != "batch" {
+		respondOpenAIError(c, http.StatusBadRequest, "The input file must be uploaded with purpose 'batch'", "invalid_input_file")
+		return
+	}
+	now := helper.GetTimestamp()
+	batch := &model.Batch{
+		BatchId:          "batch_" + random.GetUUID(),
+		UserId:           userId,
+		TokenId:          c.GetInt(ctxkey.TokenId),
+		Endpoint:         request.Endpoint,
+		InputFileId:      request.InputFileId,
+		CompletionWindow: request.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		CreatedTime:      now,
+		ExpiresTime:      now + int64((24 * time.Hour).Seconds()),
+	}
+	if len(request.Metadata) > 0 {
+		jsonBytes, _ := json.Marshal(request.Metadata)
+		metadata := string(jsonBytes)
+		batch.Metadata = &metadata
+	}
+	err = batch.Insert()
+	if err != nil {
+		respondOpenAIError(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	notifyBatchExecutor()
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func getRequestBatch(c *gin.Context) (*model.Batch, bool) {
+	batch, err := model.GetBatchByBatchId(c.Param("id"), c.GetInt(ctxkey.Id))
+	if err != nil {
+		respondOpenAIError(c, http.StatusNotFound, fmt.Sprintf("No such Batch object: %s", c.Param("id")), "")
+		return nil, false
+	}
+	return batch, true
+}
+
+func RetrieveBatch(c *gin.Context) {
+	batch, ok := getRequestBatch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func CancelBatch(c *gin.Context) {
+	batch, ok := getRequestBatch(c)
+	if !ok {
+		return
+	}
+	now := helper.GetTimestamp()
+	// a batch the executor has not picked up yet has nothing to wind down
+	cancelled, err := model.UpdateBatchStatus(batch.Id, []string{model.BatchStatusValidating}, model.BatchStatusCancelled, "cancelled_time", now)
+	if err == nil && !cancelled {
+		_, err = model.UpdateBatchStatus(batch.Id, []string{model.BatchStatusInProgress}, model.BatchStatusCancelling, "cancelling_time", now)
+	}
+	if err != nil {
+		respondOpenAIError(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	batch, err = model.GetBatchById(batch.Id)
+	if err != nil {
+		respondOpenAIError(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	if batch.Status != model.BatchStatusCancelling && batch.Status != model.BatchStatusCancelled {
+		respondOpenAIError(c, http.StatusConflict, fmt.Sprintf("Cannot cancel a batch with status '%s'", batch.Status), "invalid_batch_status")
+		return
+	}
+	c.JSON(http.StatusOK, toBatchObject(batch))
+}
+
+func ListBatches(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	batches, err := model.GetUserBatches(c.GetInt(ctxkey.Id), c.Query("after"), limit+1)
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	data := make([]batchObject, 0, len(batches))
+	for _, batch := range batches {
+		data = append(data, toBatchObject(batch))
+	}
+	response := gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+	}
+	if len(data) > 0 {
+		response["first_id"] = data[0].Id
+		response["last_id"] = data[len(data)-1].Id
+	}
+	c.JSON(http.StatusOK, response)
+}
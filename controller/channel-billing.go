@@ -313,6 +313,10 @@ func updateChannelBalance(channel *model.Channel) (float64, error) {
 	if channel.GetBaseURL() == "" {
 		channel.BaseURL = &baseURL
 	}
+	// the balance of a multi-key channel is that of its first key
+	if keys := channel.GetKeys(); len(keys) > 0 {
+		channel.Key = keys[0]
+	}
 	switch channel.Type {
 	case channeltype.OpenAI:
 		if channel.GetBaseURL() != "" {
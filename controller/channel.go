@@ -86,6 +86,10 @@ func AddChannel(c *gin.Context) {
 	}
 	channel.CreatedTime = helper.GetTimestamp()
 	keys := strings.Split(channel.Key, "\n")
+	if cfg, _ := channel.LoadConfig(); cfg.MultiKey {
+		// a multi-key channel keeps all keys, instead of one channel per key
+		keys = []string{channel.Key}
+	}
 	channels := make([]model.Channel, 0, len(keys))
 	for _, key := range keys {
 		if key == "" {
@@ -155,6 +159,8 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	// key status is only changed through UpdateChannelKeyStatus
+	channel.KeyStatus = nil
 	err = channel.Update()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -170,3 +176,73 @@ func UpdateChannel(c *gin.Context) {
 	})
 	return
 }
+
+func GetChannelKeys(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    channel.GetKeyHealth(),
+	})
+	return
+}
+
+type ChannelKeyStatusRequest struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+}
+
+func UpdateChannelKeyStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	request := ChannelKeyStatusRequest{}
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.Status != model.ChannelStatusEnabled && request.Status != model.ChannelStatusManuallyDisabled {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无效的密钥状态",
+		})
+		return
+	}
+	_, err = model.UpdateChannelKeyStatus(id, request.Fingerprint, request.Status, "")
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+	return
+}
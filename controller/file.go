@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/random"
+	"github.com/LeXwDeX/one-api/common/storage"
+	"github.com/LeXwDeX/one-api/model"
+	relaycontroller "github.com/LeXwDeX/one-api/relay/controller"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+// https://platform.openai.com/docs/api-reference/files
+
+var filePurposes = map[string]bool{
+	"assistants": true,
+	"batch":      true,
+	"fine-tune":  true,
+	"vision":     true,
+	"user_data":  true,
+	"evals":      true,
+}
+
+type fileObject struct {
+	Id        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+func toFileObject(file *model.File) fileObject {
+	return fileObject{
+		Id:        file.FileId,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedTime,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    model.FileStatusProcessed,
+	}
+}
+
+func respondOpenAIError(c *gin.Context, statusCode int, message string, code string) {
+	c.JSON(statusCode, gin.H{
+		"error": relaymodel.Error{
+			Message: message,
+			Type:    "invalid_request_error",
+			Code:    code,
+		},
+	})
+}
+
+// CreateFile stores a file and the record that makes it usable by id from later requests.
+func CreateFile(ctx context.Context, file *model.File, content io.Reader) error {
+	file.FileId = "file-" + random.GetUUID()
+	file.StorageKey = fmt.Sprintf("%d/%s", file.UserId, file.FileId)
+	file.CreatedTime = helper.GetTimestamp()
+	err := storage.Default().Put(ctx, file.StorageKey, content, file.Bytes)
+	if err != nil {
+		return err
+	}
+	err = file.Insert()
+	if err != nil {
+		_ = storage.Default().Delete(ctx, file.StorageKey)
+	}
+	return err
+}
+
+func UploadFile(c *gin.Context) {
+	ctx := c.Request.Context()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(config.FileMaxSize)<<20)
+	purpose := c.PostForm("purpose")
+	if !filePurposes[purpose] {
+		respondOpenAIError(c, http.StatusBadRequest, fmt.Sprintf("Invalid purpose: '%s'", purpose), "invalid_purpose")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, "Invalid file: "+err.Error(), "invalid_file")
+		return
+	}
+	content, err := header.Open()
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, "Invalid file: "+err.Error(), "invalid_file")
+		return
+	}
+	defer content.Close()
+	contentType := mime.TypeByExtension(filepath.Ext(header.Filename))
+	if contentType == "" {
+		contentType = header.Header.Get("Content-Type")
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	file := &model.File{
+		UserId:      c.GetInt(ctxkey.Id),
+		TokenId:     c.GetInt(ctxkey.TokenId),
+		Filename:    header.Filename,
+		Purpose:     purpose,
+		Bytes:       header.Size,
+		ContentType: contentType,
+	}
+	err = CreateFile(ctx, file, content)
+	if err != nil {
+		logger.Errorf(ctx, "failed to create file: %s", err.Error())
+		respondOpenAIError(c, http.StatusInternalServerError, "Failed to store the file", "file_storage_error")
+		return
+	}
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+func ListFiles(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	// one more than asked for, to tell whether there is another page
+	files, err := model.GetUserFiles(c.GetInt(ctxkey.Id), c.Query("purpose"), c.Query("after"), limit+1)
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, err.Error(), "")
+		return
+	}
+	hasMore := len(files) > limit
+	if hasMore {
+		files = files[:limit]
+	}
+	data := make([]fileObject, 0, len(files))
+	for _, file := range files {
+		data = append(data, toFileObject(file))
+	}
+	response := gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+	}
+	if len(data) > 0 {
+		response["first_id"] = data[0].Id
+		response["last_id"] = data[len(data)-1].Id
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func getRequestFile(c *gin.Context) (*model.File, bool) {
+	file, err := model.GetFileByFileId(c.Param("id"), c.GetInt(ctxkey.Id))
+	if err != nil {
+		respondOpenAIError(c, http.StatusNotFound, fmt.Sprintf("No such File object: %s", c.Param("id")), "")
+		return nil, false
+	}
+	return file, true
+}
+
+func RetrieveFile(c *gin.Context) {
+	file, ok := getRequestFile(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toFileObject(file))
+}
+
+func RetrieveFileContent(c *gin.Context) {
+	ctx := c.Request.Context()
+	file, ok := getRequestFile(c)
+	if !ok {
+		return
+	}
+	content, err := storage.Default().Get(ctx, file.StorageKey)
+	if err != nil {
+		logger.Errorf(ctx, "failed to read file %s: %s", file.FileId, err.Error())
+		respondOpenAIError(c, http.StatusInternalServerError, "Failed to read the file", "file_storage_error")
+		return
+	}
+	defer content.Close()
+	c.DataFromReader(http.StatusOK, file.Bytes, file.ContentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename}),
+	})
+}
+
+func DeleteFile(c *gin.Context) {
+	ctx := c.Request.Context()
+	file, ok := getRequestFile(c)
+	if !ok {
+		return
+	}
+	err := file.Delete()
+	if err != nil {
+		respondOpenAIError(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	err = storage.Default().Delete(ctx, file.StorageKey)
+	if err != nil {
+		logger.Errorf(ctx, "failed to delete content of file %s: %s", file.FileId, err.Error())
+	}
+	go relaycontroller.DeleteUpstreamFiles(context.Background(), file)
+	c.JSON(http.StatusOK, gin.H{
+		"id":      file.FileId,
+		"object":  "file",
+		"deleted": true,
+	})
+}
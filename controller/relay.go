@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/circuitbreaker"
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/ctxkey"
 	"github.com/LeXwDeX/one-api/common/helper"
 	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/common/routing"
 	"github.com/LeXwDeX/one-api/middleware"
 	dbmodel "github.com/LeXwDeX/one-api/model"
 	"github.com/LeXwDeX/one-api/monitor"
+	"github.com/LeXwDeX/one-api/relay/adaptor/anthropic"
+	"github.com/LeXwDeX/one-api/relay/adaptor/gemini"
 	"github.com/LeXwDeX/one-api/relay/controller"
 	"github.com/LeXwDeX/one-api/relay/model"
 	"github.com/LeXwDeX/one-api/relay/relaymode"
@@ -26,7 +33,7 @@ import (
 func relayHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
 	var err *model.ErrorWithStatusCode
 	switch relayMode {
-	case relaymode.ImagesGenerations:
+	case relaymode.ImagesGenerations, relaymode.ImagesEdits, relaymode.ImagesVariations:
 		err = controller.RelayImageHelper(c, relayMode)
 	case relaymode.AudioSpeech:
 		fallthrough
@@ -36,12 +43,57 @@ func relayHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
 		err = controller.RelayAudioHelper(c, relayMode)
 	case relaymode.Proxy:
 		err = controller.RelayProxyHelper(c, relayMode)
+	case relaymode.Responses:
+		err = controller.RelayResponsesHelper(c)
+	case relaymode.Messages:
+		err = controller.RelayMessagesHelper(c)
+	case relaymode.GeminiGenerateContent, relaymode.GeminiEmbedContent:
+		err = controller.RelayGeminiHelper(c, relayMode)
+	case relaymode.Realtime:
+		err = controller.RelayRealtimeHelper(c)
+	case relaymode.Rerank:
+		err = controller.RelayRerankHelper(c)
 	default:
 		err = controller.RelayTextHelper(c)
 	}
 	return err
 }
 
+// relayHelperWithFeedback feeds the outcome and latency of the relay to the circuit breaker
+// and to latency-aware routing.
+func relayHelperWithFeedback(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
+	startTime := time.Now()
+	c.Set(ctxkey.UpstreamFirstByteLatency, time.Duration(0))
+	c.Set(ctxkey.ResponseCacheHit, false)
+	bizErr := relayHelper(c, relayMode)
+	if c.GetBool(ctxkey.ResponseCacheHit) {
+		// the channel was never asked
+		return bizErr
+	}
+	if c.Request.Context().Err() != nil {
+		// the client went away, which says nothing about the channel
+		return bizErr
+	}
+	channelId := c.GetInt(ctxkey.ChannelId)
+	modelName := c.GetString(ctxkey.OriginalModel)
+	if fallbackModel := c.GetString(ctxkey.FallbackModel); fallbackModel != "" {
+		modelName = fallbackModel
+	}
+	totalLatency := time.Since(startTime)
+	if relayMode == relaymode.Realtime {
+		// a realtime session lasts as long as the client likes, only the handshake tells about the channel
+		totalLatency = c.GetDuration(ctxkey.UpstreamFirstByteLatency)
+	}
+	// client errors say nothing about the health of the channel
+	if bizErr == nil || bizErr.StatusCode != http.StatusBadRequest {
+		circuitbreaker.Record(channelId, modelName, bizErr == nil, totalLatency)
+	}
+	if bizErr == nil {
+		routing.RecordLatency(channelId, modelName, c.GetDuration(ctxkey.UpstreamFirstByteLatency), totalLatency)
+	}
+	return bizErr
+}
+
 func Relay(c *gin.Context) {
 	ctx := c.Request.Context()
 	relayMode := relaymode.GetByPath(c.Request.URL.Path)
@@ -51,16 +103,21 @@ func Relay(c *gin.Context) {
 	}
 	channelId := c.GetInt(ctxkey.ChannelId)
 	userId := c.GetInt(ctxkey.Id)
-	bizErr := relayHelper(c, relayMode)
+	bizErr := relayHelperWithFeedback(c, relayMode)
 	if bizErr == nil {
 		monitor.Emit(channelId, true)
 		return
 	}
+	if ctx.Err() != nil {
+		logger.Warnf(ctx, "client aborted the request, won't retry: %s", bizErr.Error.Message)
+		return
+	}
 	lastFailedChannelId := channelId
 	channelName := c.GetString(ctxkey.ChannelName)
+	channelKey := c.GetString(ctxkey.ChannelKey)
 	group := c.GetString(ctxkey.Group)
 	originalModel := c.GetString(ctxkey.OriginalModel)
-	go processChannelRelayError(ctx, userId, channelId, channelName, *bizErr)
+	go processChannelRelayError(ctx, userId, channelId, channelName, channelKey, *bizErr)
 	requestId := c.GetString(helper.RequestIdKey)
 	retryTimes := config.RetryTimes
 	if !shouldRetry(c, bizErr.StatusCode) {
@@ -80,14 +137,22 @@ func Relay(c *gin.Context) {
 		middleware.SetupContextForSelectedChannel(c, channel, originalModel)
 		requestBody, err := common.GetRequestBody(c)
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
-		bizErr = relayHelper(c, relayMode)
+		bizErr = relayHelperWithFeedback(c, relayMode)
 		if bizErr == nil {
 			return
 		}
+		if ctx.Err() != nil {
+			logger.Warnf(ctx, "client aborted the request, won't retry: %s", bizErr.Error.Message)
+			return
+		}
 		channelId := c.GetInt(ctxkey.ChannelId)
 		lastFailedChannelId = channelId
 		channelName := c.GetString(ctxkey.ChannelName)
-		go processChannelRelayError(ctx, userId, channelId, channelName, *bizErr)
+		channelKey := c.GetString(ctxkey.ChannelKey)
+		go processChannelRelayError(ctx, userId, channelId, channelName, channelKey, *bizErr)
+	}
+	if bizErr != nil && shouldRetry(c, bizErr.StatusCode) {
+		bizErr = relayFallback(c, relayMode, group, originalModel, bizErr)
 	}
 	if bizErr != nil {
 		if bizErr.StatusCode == http.StatusTooManyRequests {
@@ -96,12 +161,60 @@ func Relay(c *gin.Context) {
 
 		// BUG: bizErr is in race condition
 		bizErr.Error.Message = helper.MessageWithRequestId(bizErr.Error.Message, requestId)
+		if c.Writer.Written() && strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+			// the stream was already opened by the heartbeats, the error can only be sent as an event
+			if relayMode == relaymode.Messages {
+				_ = render.EventData(c, render.Event{Event: "error", Data: anthropic.ErrorResponseOpenAI2Claude(bizErr)})
+				return
+			}
+			_ = render.ObjectData(c, gin.H{"error": bizErr.Error})
+			return
+		}
+		switch relayMode {
+		case relaymode.Messages:
+			c.JSON(bizErr.StatusCode, anthropic.ErrorResponseOpenAI2Claude(bizErr))
+			return
+		case relaymode.GeminiGenerateContent, relaymode.GeminiEmbedContent:
+			c.JSON(bizErr.StatusCode, gemini.ErrorResponseOpenAI2Gemini(bizErr))
+			return
+		}
 		c.JSON(bizErr.StatusCode, gin.H{
 			"error": bizErr.Error,
 		})
 	}
 }
 
+// relayFallback tries the fallback chain of the original model once the retries are exhausted,
+// giving each fallback model a single attempt. Models before the one already in use are skipped.
+func relayFallback(c *gin.Context, relayMode int, group string, originalModel string, bizErr *model.ErrorWithStatusCode) *model.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	userId := c.GetInt(ctxkey.Id)
+	tried := map[string]bool{}
+	if fallbackModel := c.GetString(ctxkey.FallbackModel); fallbackModel != "" {
+		tried[fallbackModel] = true
+	}
+	for {
+		channel, fallbackModel := middleware.GetFallbackChannel(c, group, originalModel, c.GetString(ctxkey.FallbackModel), tried)
+		if channel == nil {
+			break
+		}
+		tried[fallbackModel] = true
+		logger.Infof(ctx, "falling back from model %s to %s on channel #%d", originalModel, fallbackModel, channel.Id)
+		middleware.SetupContextForFallbackModel(c, channel, originalModel, fallbackModel)
+		requestBody, _ := common.GetRequestBody(c)
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		bizErr = relayHelperWithFeedback(c, relayMode)
+		if bizErr == nil || ctx.Err() != nil {
+			return bizErr
+		}
+		go processChannelRelayError(ctx, userId, channel.Id, channel.Name, c.GetString(ctxkey.ChannelKey), *bizErr)
+		if !shouldRetry(c, bizErr.StatusCode) {
+			break
+		}
+	}
+	return bizErr
+}
+
 func shouldRetry(c *gin.Context, statusCode int) bool {
 	if _, ok := c.Get(ctxkey.SpecificChannelId); ok {
 		return false
@@ -121,16 +234,38 @@ func shouldRetry(c *gin.Context, statusCode int) bool {
 	return true
 }
 
-func processChannelRelayError(ctx context.Context, userId int, channelId int, channelName string, err model.ErrorWithStatusCode) {
+func processChannelRelayError(ctx context.Context, userId int, channelId int, channelName string, channelKey string, err model.ErrorWithStatusCode) {
 	logger.Errorf(ctx, "relay error (channel id %d, user id: %d): %s", channelId, userId, err.Message)
 	// https://platform.openai.com/docs/guides/error-codes/api-errors
 	if monitor.ShouldDisableChannel(&err.Error, err.StatusCode) {
-		monitor.DisableChannel(channelId, channelName, err.Message)
+		if channelKey != "" {
+			monitor.DisableChannelKey(channelId, channelName, channelKey, err.Message)
+		} else {
+			monitor.DisableChannel(channelId, channelName, err.Message)
+		}
 	} else {
 		monitor.Emit(channelId, false)
 	}
 }
 
+// Tokenize tells how a request would be counted and pre-consumed, without relaying it.
+func Tokenize(c *gin.Context) {
+	if bizErr := controller.RelayTokenizeHelper(c); bizErr != nil {
+		bizErr.Error.Message = helper.MessageWithRequestId(bizErr.Error.Message, c.GetString(helper.RequestIdKey))
+		c.JSON(bizErr.StatusCode, gin.H{
+			"error": bizErr.Error,
+		})
+	}
+}
+
+// CountTokens is the Anthropic flavor of Tokenize.
+func CountTokens(c *gin.Context) {
+	if bizErr := controller.RelayCountTokensHelper(c); bizErr != nil {
+		bizErr.Error.Message = helper.MessageWithRequestId(bizErr.Error.Message, c.GetString(helper.RequestIdKey))
+		c.JSON(bizErr.StatusCode, anthropic.ErrorResponseOpenAI2Claude(bizErr))
+	}
+}
+
 func RelayNotImplemented(c *gin.Context) {
 	err := model.Error{
 		Message: "API not implemented",
@@ -150,6 +150,9 @@ func AddToken(c *gin.Context) {
 		UnlimitedQuota: token.UnlimitedQuota,
 		Models:         token.Models,
 		Subnet:         token.Subnet,
+		RpmLimit:       token.RpmLimit,
+		TpmLimit:       token.TpmLimit,
+		ResponseCache:  token.ResponseCache,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -239,6 +242,9 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.Models = token.Models
 		cleanToken.Subnet = token.Subnet
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.ResponseCache = token.ResponseCache
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -93,6 +93,9 @@ func main() {
 	}
 	openai.InitTokenEncoders()
 	client.Init()
+	if config.IsMasterNode {
+		go controller.RunBatchExecutor()
+	}
 
 	// Initialize i18n
 	if err := i18n.Init(); err != nil {
@@ -92,6 +92,21 @@ func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 		key := c.Request.Header.Get("Authorization")
+		if key == "" {
+			// Anthropic clients send the key in x-api-key
+			key = c.Request.Header.Get("x-api-key")
+		}
+		if key == "" {
+			// Gemini clients send the key in x-goog-api-key or the key query parameter
+			key = c.Request.Header.Get("x-goog-api-key")
+		}
+		if key == "" {
+			// browsers can't set headers on a WebSocket, so Realtime clients send the key as a subprotocol
+			key = getWebSocketProtocolKey(c.Request.Header.Get("Sec-WebSocket-Protocol"))
+		}
+		if key == "" {
+			key = c.Query("key")
+		}
 		key = strings.TrimPrefix(key, "Bearer ")
 		key = strings.TrimPrefix(key, "sk-")
 		parts := strings.Split(key, "-")
@@ -132,6 +147,9 @@ func TokenAuth() func(c *gin.Context) {
 		c.Set(ctxkey.Id, token.UserId)
 		c.Set(ctxkey.TokenId, token.Id)
 		c.Set(ctxkey.TokenName, token.Name)
+		c.Set(ctxkey.TokenRpmLimit, token.RpmLimit)
+		c.Set(ctxkey.TokenTpmLimit, token.TpmLimit)
+		c.Set(ctxkey.TokenResponseCache, token.ResponseCache)
 		if len(parts) > 1 {
 			if model.IsAdmin(token.UserId) {
 				c.Set(ctxkey.SpecificChannelId, parts[1])
@@ -157,6 +175,15 @@ func shouldCheckModel(c *gin.Context) bool {
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/chat/completions") {
 		return true
 	}
+	if strings.HasPrefix(c.Request.URL.Path, "/v1/responses") {
+		return true
+	}
+	if strings.HasPrefix(c.Request.URL.Path, "/v1/messages") {
+		return true
+	}
+	if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models") {
+		return true
+	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/images") {
 		return true
 	}
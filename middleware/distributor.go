@@ -9,6 +9,7 @@ import (
 
 	"github.com/LeXwDeX/one-api/common/ctxkey"
 	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/routing"
 	"github.com/LeXwDeX/one-api/model"
 	"github.com/LeXwDeX/one-api/relay/channeltype"
 )
@@ -46,6 +47,12 @@ func Distribute() func(c *gin.Context) {
 			var err error
 			channel, err = model.CacheGetRandomSatisfiedChannel(userGroup, requestModel, false)
 			if err != nil {
+				if fallbackChannel, fallbackModel := GetFallbackChannel(c, userGroup, requestModel, "", nil); fallbackChannel != nil {
+					logger.Infof(ctx, "no channel for model %s, falling back to %s on channel #%d", requestModel, fallbackModel, fallbackChannel.Id)
+					SetupContextForFallbackModel(c, fallbackChannel, requestModel, fallbackModel)
+					c.Next()
+					return
+				}
 				message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", userGroup, requestModel)
 				if channel != nil {
 					logger.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
@@ -70,7 +77,14 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	}
 	c.Set(ctxkey.ModelMapping, channel.GetModelMapping())
 	c.Set(ctxkey.OriginalModel, modelName) // for retry
-	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", channel.Key))
+	c.Set(ctxkey.FallbackModel, "")
+	key := channel.PickKey()
+	c.Request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	c.Set(ctxkey.ChannelKey, "")
+	if len(channel.GetKeys()) > 1 {
+		// remembered so that a failing key can be disabled alone
+		c.Set(ctxkey.ChannelKey, model.KeyFingerprint(key))
+	}
 	c.Set(ctxkey.BaseURL, channel.GetBaseURL())
 	cfg, _ := channel.LoadConfig()
 	// this is for backward compatibility
@@ -100,3 +114,48 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	}
 	c.Set(ctxkey.Config, cfg)
 }
+
+// GetFallbackChannel walks the fallback chain of the requested model, starting after the model
+// given by after (empty for the head of the chain), and returns the first model the token may
+// use that has an available channel. Models in tried are skipped.
+func GetFallbackChannel(c *gin.Context, group string, requestModel string, after string, tried map[string]bool) (*model.Channel, string) {
+	fallbackModels := routing.GetFallbackModels(group, requestModel)
+	if after != "" {
+		for i, fallbackModel := range fallbackModels {
+			if fallbackModel == after {
+				fallbackModels = fallbackModels[i+1:]
+				break
+			}
+		}
+	}
+	for _, fallbackModel := range fallbackModels {
+		if fallbackModel == requestModel || tried[fallbackModel] {
+			continue
+		}
+		if availableModels := c.GetString(ctxkey.AvailableModels); availableModels != "" && !isModelInList(fallbackModel, availableModels) {
+			continue
+		}
+		channel, err := model.CacheGetRandomSatisfiedChannel(group, fallbackModel, false)
+		if err == nil {
+			return channel, fallbackModel
+		}
+	}
+	return nil, ""
+}
+
+// SetupContextForFallbackModel routes the request to fallbackModel on the given channel while
+// keeping requestModel as the original model, so that billing and logs see both.
+func SetupContextForFallbackModel(c *gin.Context, channel *model.Channel, requestModel string, fallbackModel string) {
+	SetupContextForSelectedChannel(c, channel, requestModel)
+	channelMapping := channel.GetModelMapping()
+	modelMapping := make(map[string]string, len(channelMapping)+1)
+	for k, v := range channelMapping {
+		modelMapping[k] = v
+	}
+	modelMapping[requestModel] = fallbackModel
+	if mapped := channelMapping[fallbackModel]; mapped != "" {
+		modelMapping[requestModel] = mapped
+	}
+	c.Set(ctxkey.ModelMapping, modelMapping)
+	c.Set(ctxkey.FallbackModel, fallbackModel)
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeXwDeX/one-api/common"
 	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
 )
 
 var timeFormat = "2006-01-02T15:04:05.000Z"
@@ -109,3 +114,51 @@ func DownloadRateLimit() func(c *gin.Context) {
 func UploadRateLimit() func(c *gin.Context) {
 	return rateLimitFactory(config.UploadRateLimitNum, config.UploadRateLimitDuration, "UP")
 }
+
+func setRateLimitHeaders(c *gin.Context, kind string, status *ratelimit.Status) {
+	if status == nil {
+		return
+	}
+	c.Header("x-ratelimit-limit-"+kind, strconv.FormatInt(status.Limit, 10))
+	c.Header("x-ratelimit-remaining-"+kind, strconv.FormatInt(status.Remaining, 10))
+	c.Header("x-ratelimit-reset-"+kind, fmt.Sprintf("%ds", status.ResetAfter))
+}
+
+// TokenRateLimit enforces the RPM and TPM limits of the token and of the user's group,
+// it must run after TokenAuth. Tokens are charged once the usage of the request is known.
+func TokenRateLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userId := c.GetInt(ctxkey.Id)
+		group, err := model.CacheGetUserGroup(userId)
+		if err != nil {
+			abortWithMessage(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		tokenLimit := ratelimit.Limit{
+			RPM: c.GetInt(ctxkey.TokenRpmLimit),
+			TPM: c.GetInt(ctxkey.TokenTpmLimit),
+		}
+		scopes := ratelimit.GetScopes(c.GetInt(ctxkey.TokenId), tokenLimit, userId, group)
+		if len(scopes) == 0 {
+			c.Next()
+			return
+		}
+		result, err := ratelimit.Check(ctx, scopes)
+		if err != nil {
+			// do not block requests when the limiter backend is unavailable
+			logger.Error(ctx, "rate limit check failed: "+err.Error())
+			c.Next()
+			return
+		}
+		setRateLimitHeaders(c, "requests", result.Requests)
+		setRateLimitHeaders(c, "tokens", result.Tokens)
+		if result.Exceeded {
+			c.Header("Retry-After", strconv.FormatInt(result.RetryAfter, 10))
+			abortWithMessage(c, http.StatusTooManyRequests, fmt.Sprintf("请求过于频繁，请在 %d 秒后重试", result.RetryAfter))
+			return
+		}
+		c.Set(ctxkey.RateLimitScopes, scopes)
+		c.Next()
+	}
+}
@@ -21,6 +21,10 @@ func abortWithMessage(c *gin.Context, statusCode int, message string) {
 }
 
 func getRequestModel(c *gin.Context) (string, error) {
+	if strings.HasPrefix(c.Request.URL.Path, "/v1/realtime") {
+		// the WebSocket handshake carries the model in the query, e.g. /v1/realtime?model=gpt-realtime
+		return c.Query("model"), nil
+	}
 	var modelRequest ModelRequest
 	err := common.UnmarshalBodyReusable(c, &modelRequest)
 	if err != nil {
@@ -36,7 +40,7 @@ func getRequestModel(c *gin.Context) (string, error) {
 			modelRequest.Model = c.Param("model")
 		}
 	}
-	if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
+	if strings.HasPrefix(c.Request.URL.Path, "/v1/images/") {
 		if modelRequest.Model == "" {
 			modelRequest.Model = "dall-e-2"
 		}
@@ -46,6 +50,10 @@ func getRequestModel(c *gin.Context) (string, error) {
 			modelRequest.Model = "whisper-1"
 		}
 	}
+	if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models/") {
+		// Gemini-native routes carry the model in the path, e.g. /v1beta/models/gemini-pro:generateContent
+		modelRequest.Model = strings.SplitN(c.Param("model"), ":", 2)[0]
+	}
 	return modelRequest.Model, nil
 }
 
@@ -58,3 +66,15 @@ func isModelInList(modelName string, models string) bool {
 	}
 	return false
 }
+
+// getWebSocketProtocolKey finds the key in the subprotocols of a Realtime handshake,
+// e.g. "realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1".
+func getWebSocketProtocolKey(protocols string) string {
+	for _, protocol := range strings.Split(protocols, ",") {
+		protocol = strings.TrimSpace(protocol)
+		if strings.HasPrefix(protocol, "openai-insecure-api-key.") {
+			return strings.TrimPrefix(protocol, "openai-insecure-api-key.")
+		}
+	}
+	return ""
+}
@@ -2,12 +2,17 @@ package model
 
 import (
 	"context"
+	"math"
+	"math/rand"
 	"sort"
 	"strings"
 
 	"gorm.io/gorm"
 
 	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/circuitbreaker"
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/routing"
 	"github.com/LeXwDeX/one-api/common/utils"
 )
 
@@ -20,7 +25,6 @@ type Ability struct {
 }
 
 func GetRandomSatisfiedChannel(group string, model string, ignoreFirstPriority bool) (*Channel, error) {
-	ability := Ability{}
 	groupCol := "`group`"
 	trueVal := "1"
 	if common.UsingPostgreSQL {
@@ -28,26 +32,149 @@ func GetRandomSatisfiedChannel(group string, model string, ignoreFirstPriority b
 		trueVal = "true"
 	}
 
-	var err error = nil
-	var channelQuery *gorm.DB
-	if ignoreFirstPriority {
-		channelQuery = DB.Where(groupCol+" = ? and model = ? and enabled = "+trueVal, group, model)
-	} else {
-		maxPrioritySubQuery := DB.Model(&Ability{}).Select("MAX(priority)").Where(groupCol+" = ? and model = ? and enabled = "+trueVal, group, model)
-		channelQuery = DB.Where(groupCol+" = ? and model = ? and enabled = "+trueVal+" and priority = (?)", group, model, maxPrioritySubQuery)
+	var abilities []Ability
+	err := DB.Where(groupCol+" = ? and model = ? and enabled = "+trueVal, group, model).Find(&abilities).Error
+	if err != nil {
+		return nil, err
 	}
-	if common.UsingSQLite || common.UsingPostgreSQL {
-		err = channelQuery.Order("RANDOM()").First(&ability).Error
-	} else {
-		err = channelQuery.Order("RAND()").First(&ability).Error
+	if len(abilities) == 0 {
+		return nil, gorm.ErrRecordNotFound
 	}
+	channelIds := make([]int, 0, len(abilities))
+	for _, ability := range abilities {
+		channelIds = append(channelIds, ability.ChannelId)
+	}
+	var channels []*Channel
+	err = DB.Where("id in ?", channelIds).Find(&channels).Error
 	if err != nil {
 		return nil, err
 	}
-	channel := Channel{}
-	channel.Id = ability.ChannelId
-	err = DB.First(&channel, "id = ?", ability.ChannelId).Error
-	return &channel, err
+	if len(channels) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// selected as from the channel cache, so that routing doesn't depend on it
+	sort.SliceStable(channels, func(i, j int) bool {
+		return channels[i].GetPriority() > channels[j].GetPriority()
+	})
+	channel := pickChannel(selectChannels(channels, model, ignoreFirstPriority), group, model)
+	if channel == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	circuitbreaker.Acquire(channel.Id, model)
+	return channel, nil
+}
+
+// selectChannels returns the channels to pick from, out of channels sorted by priority: those of
+// the highest priority, or of the lower ones with ignoreFirstPriority. Tripped channels are
+// skipped, falling back to lower priorities before using tripped ones.
+func selectChannels(channels []*Channel, model string, ignoreFirstPriority bool) []*Channel {
+	endIdx := len(channels)
+	// choose by priority
+	firstChannel := channels[0]
+	if firstChannel.GetPriority() > 0 {
+		for i := range channels {
+			if channels[i].GetPriority() != firstChannel.GetPriority() {
+				endIdx = i
+				break
+			}
+		}
+	}
+	candidates := channels[:endIdx]
+	if ignoreFirstPriority {
+		if endIdx < len(channels) { // which means there are more than one priority
+			candidates = channels[endIdx:]
+		}
+	}
+	fallback := candidates
+	if !ignoreFirstPriority && endIdx < len(channels) {
+		fallback = filterAvailableChannels(channels[endIdx:], model, candidates)
+	}
+	return filterAvailableChannels(candidates, model, fallback)
+}
+
+// filterAvailableChannels drops the channels tripped by the circuit breaker, and returns fallback if none is left.
+func filterAvailableChannels(channels []*Channel, model string, fallback []*Channel) []*Channel {
+	available := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if circuitbreaker.Available(channel.Id, model) {
+			available = append(available, channel)
+		}
+	}
+	if len(available) == 0 {
+		return fallback
+	}
+	return available
+}
+
+// pickChannelByWeight picks a channel at random, proportionally to its weight.
+// Zero-weight channels are standby only: they are chosen (uniformly) only
+// when no channel in the candidate set has a positive weight.
+func pickChannelByWeight(channels []*Channel) *Channel {
+	if len(channels) == 0 {
+		return nil
+	}
+	var totalWeight uint64
+	for _, channel := range channels {
+		totalWeight += uint64(channel.GetWeight())
+	}
+	if totalWeight == 0 {
+		return channels[rand.Intn(len(channels))]
+	}
+	r := uint64(rand.Int63n(int64(totalWeight)))
+	for _, channel := range channels {
+		weight := uint64(channel.GetWeight())
+		if r < weight {
+			return channel
+		}
+		r -= weight
+	}
+	return channels[len(channels)-1]
+}
+
+// pickChannel picks a channel with the routing strategy configured for the group or model.
+func pickChannel(channels []*Channel, group string, model string) *Channel {
+	if routing.GetStrategy(group, model) == routing.StrategyLatency {
+		if channel := pickChannelByLatency(channels, model); channel != nil {
+			return channel
+		}
+	}
+	return pickChannelByWeight(channels)
+}
+
+// pickChannelByLatency picks a channel at random, in inverse proportion to the square of its
+// observed latency. It returns nil unless every channel has enough samples, in which case the
+// caller falls back to weighted random picking, which also gathers the missing samples.
+func pickChannelByLatency(channels []*Channel, model string) *Channel {
+	if len(channels) <= 1 {
+		return nil
+	}
+	scores := make([]float64, len(channels))
+	var totalScore float64
+	for i, channel := range channels {
+		stats, ok := routing.GetLatency(channel.Id, model)
+		if !ok {
+			return nil
+		}
+		// time to first byte is comparable across stream and non-stream requests, prefer it
+		latency := stats.TTFB
+		if stats.TTFBSamples < config.LatencyRoutingMinSamples {
+			if stats.TotalSamples < config.LatencyRoutingMinSamples {
+				return nil
+			}
+			latency = stats.Total
+		}
+		milliseconds := math.Max(float64(latency.Milliseconds()), 1)
+		scores[i] = 1 / (milliseconds * milliseconds)
+		totalScore += scores[i]
+	}
+	r := rand.Float64() * totalScore
+	for i, channel := range channels {
+		if r < scores[i] {
+			return channel
+		}
+		r -= scores[i]
+	}
+	return channels[len(channels)-1]
 }
 
 func (channel *Channel) AddAbilities() error {
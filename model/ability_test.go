@@ -0,0 +1,95 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LeXwDeX/one-api/common/circuitbreaker"
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/routing"
+)
+
+func newWeightedChannel(id int, weight uint) *Channel {
+	return &Channel{Id: id, Weight: &weight}
+}
+
+func TestPickChannelByWeightProportional(t *testing.T) {
+	channels := []*Channel{
+		newWeightedChannel(1, 70),
+		newWeightedChannel(2, 20),
+		newWeightedChannel(3, 10),
+	}
+	counts := make(map[int]int)
+	const rounds = 100000
+	for i := 0; i < rounds; i++ {
+		counts[pickChannelByWeight(channels).Id]++
+	}
+	assert.InDelta(t, 0.7, float64(counts[1])/rounds, 0.02)
+	assert.InDelta(t, 0.2, float64(counts[2])/rounds, 0.02)
+	assert.InDelta(t, 0.1, float64(counts[3])/rounds, 0.02)
+}
+
+func TestPickChannelByWeightZeroIsStandby(t *testing.T) {
+	channels := []*Channel{
+		newWeightedChannel(1, 0),
+		newWeightedChannel(2, 5),
+		{Id: 3},
+	}
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, 2, pickChannelByWeight(channels).Id)
+	}
+}
+
+func TestPickChannelByWeightAllZero(t *testing.T) {
+	channels := []*Channel{
+		newWeightedChannel(1, 0),
+		{Id: 2},
+	}
+	seen := make(map[int]bool)
+	for i := 0; i < 1000; i++ {
+		seen[pickChannelByWeight(channels).Id] = true
+	}
+	assert.True(t, seen[1])
+	assert.True(t, seen[2])
+	assert.Nil(t, pickChannelByWeight(nil))
+}
+
+func TestPickChannelByLatency(t *testing.T) {
+	channels := []*Channel{newWeightedChannel(101, 0), newWeightedChannel(102, 0)}
+	// no data yet, the caller falls back to weighted picking
+	assert.Nil(t, pickChannelByLatency(channels, "gpt-4o"))
+
+	for i := 0; i < config.LatencyRoutingMinSamples; i++ {
+		routing.RecordLatency(101, "gpt-4o", 100*time.Millisecond, time.Second)
+		routing.RecordLatency(102, "gpt-4o", 300*time.Millisecond, time.Second)
+	}
+	counts := make(map[int]int)
+	const rounds = 10000
+	for i := 0; i < rounds; i++ {
+		counts[pickChannelByLatency(channels, "gpt-4o").Id]++
+	}
+	// weights are 1/100² and 1/300², i.e. 9:1
+	assert.InDelta(t, 0.9, float64(counts[101])/rounds, 0.02)
+}
+
+func TestSelectChannelsSkipsTrippedPriority(t *testing.T) {
+	saved := config.CircuitBreakerEnabled
+	config.CircuitBreakerEnabled = true
+	config.CircuitBreakerFailureThreshold = 1
+	config.CircuitBreakerOpenDuration = 60
+	defer func() { config.CircuitBreakerEnabled = saved }()
+
+	high, low := int64(10), int64(1)
+	channels := []*Channel{{Id: 201, Priority: &high}, {Id: 202, Priority: &low}}
+	assert.Equal(t, channels[:1], selectChannels(channels, "gpt-4o", false))
+	assert.Equal(t, channels[1:], selectChannels(channels, "gpt-4o", true))
+
+	// the lower priority is used before the tripped higher one
+	circuitbreaker.Record(201, "gpt-4o", false, time.Second)
+	assert.Equal(t, channels[1:], selectChannels(channels, "gpt-4o", false))
+	// and the tripped one at last
+	circuitbreaker.Record(202, "gpt-4o", false, time.Second)
+	assert.Equal(t, channels[:1], selectChannels(channels, "gpt-4o", false))
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+)
+
+// https://platform.openai.com/docs/api-reference/batch/object
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// Batch is a job of the Batch API, whose requests are read from an uploaded JSONL file
+// and relayed one by one on behalf of the token that created it.
+type Batch struct {
+	Id               int     `json:"id"`
+	BatchId          string  `json:"batch_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId           int     `json:"user_id" gorm:"index"`
+	TokenId          int     `json:"token_id"`
+	Endpoint         string  `json:"endpoint"`
+	InputFileId      string  `json:"input_file_id"`
+	CompletionWindow string  `json:"completion_window"`
+	Status           string  `json:"status" gorm:"type:varchar(32);index"`
+	OutputFileId     string  `json:"output_file_id"`
+	ErrorFileId      string  `json:"error_file_id"`
+	Errors           *string `json:"errors" gorm:"type:text"` // JSON list of validation errors
+	Metadata         *string `json:"metadata" gorm:"type:text"`
+	TotalCount       int     `json:"total_count"`
+	CompletedCount   int     `json:"completed_count"`
+	FailedCount      int     `json:"failed_count"`
+	CreatedTime      int64   `json:"created_time" gorm:"bigint"`
+	InProgressTime   int64   `json:"in_progress_time" gorm:"bigint"`
+	ExpiresTime      int64   `json:"expires_time" gorm:"bigint"`
+	FinalizingTime   int64   `json:"finalizing_time" gorm:"bigint"`
+	CompletedTime    int64   `json:"completed_time" gorm:"bigint"`
+	FailedTime       int64   `json:"failed_time" gorm:"bigint"`
+	ExpiredTime      int64   `json:"expired_time" gorm:"bigint"`
+	CancellingTime   int64   `json:"cancelling_time" gorm:"bigint"`
+	CancelledTime    int64   `json:"cancelled_time" gorm:"bigint"`
+}
+
+func (batch *Batch) Insert() error {
+	return DB.Create(batch).Error
+}
+
+// UpdateColumns saves the given columns only; the status is moved with UpdateBatchStatus instead,
+// so that a batch cancelled meanwhile is never switched back.
+func (batch *Batch) UpdateColumns(columns ...string) error {
+	return DB.Model(batch).Select(columns).Updates(batch).Error
+}
+
+func GetBatchByBatchId(batchId string, userId int) (*Batch, error) {
+	if batchId == "" || userId == 0 {
+		return nil, errors.New("batch id 或 user id 为空！")
+	}
+	batch := Batch{}
+	err := DB.Where("batch_id = ? and user_id = ?", batchId, userId).First(&batch).Error
+	return &batch, err
+}
+
+func GetBatchById(id int) (*Batch, error) {
+	batch := Batch{}
+	err := DB.First(&batch, "id = ?", id).Error
+	return &batch, err
+}
+
+// GetUserBatches lists the batches of a user, newest first, after the batch the previous page ended with.
+func GetUserBatches(userId int, after string, limit int) ([]*Batch, error) {
+	var batches []*Batch
+	query := DB.Where("user_id = ?", userId)
+	if after != "" {
+		afterBatch, err := GetBatchByBatchId(after, userId)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", afterBatch.Id)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+func GetBatchesByStatus(status string) ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("status = ?", status).Order("id asc").Find(&batches).Error
+	return batches, err
+}
+
+// UpdateBatchStatus moves the batch to status only if it is still in one of the given states,
+// and tells whether it did.
+func UpdateBatchStatus(id int, from []string, status string, timeColumn string, timestamp int64) (bool, error) {
+	result := DB.Model(&Batch{}).Where("id = ? and status in ?", id, from).Updates(map[string]any{
+		"status":   status,
+		timeColumn: timestamp,
+	})
+	return result.RowsAffected > 0, result.Error
+}
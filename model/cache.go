@@ -6,10 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/circuitbreaker"
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/logger"
-	"github.com/LeXwDeX/one-api/common/random"
-	"math/rand"
 	"sort"
 	"strconv"
 	"strings"
@@ -171,6 +170,8 @@ var group2model2channels map[string]map[string][]*Channel
 var channelSyncLock sync.RWMutex
 
 func InitChannelCache() {
+	// the key status read below supersedes the keys disabled meanwhile
+	forgetDisabledKeys()
 	newChannelId2channel := make(map[int]*Channel)
 	var channels []*Channel
 	DB.Where("status = ?", ChannelStatusEnabled).Find(&channels)
@@ -234,22 +235,7 @@ func CacheGetRandomSatisfiedChannel(group string, model string, ignoreFirstPrior
 	if len(channels) == 0 {
 		return nil, errors.New("channel not found")
 	}
-	endIdx := len(channels)
-	// choose by priority
-	firstChannel := channels[0]
-	if firstChannel.GetPriority() > 0 {
-		for i := range channels {
-			if channels[i].GetPriority() != firstChannel.GetPriority() {
-				endIdx = i
-				break
-			}
-		}
-	}
-	idx := rand.Intn(endIdx)
-	if ignoreFirstPriority {
-		if endIdx < len(channels) { // which means there are more than one priority
-			idx = random.RandRange(endIdx, len(channels))
-		}
-	}
-	return channels[idx], nil
+	channel := pickChannel(selectChannels(channels, model, ignoreFirstPriority), group, model)
+	circuitbreaker.Acquire(channel.Id, model)
+	return channel, nil
 }
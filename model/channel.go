@@ -1,13 +1,21 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/helper"
 	"github.com/LeXwDeX/one-api/common/logger"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -38,6 +46,7 @@ type Channel struct {
 	Priority           *int64  `json:"priority" gorm:"bigint;default:0"`
 	Config             string  `json:"config"`
 	SystemPrompt       *string `json:"system_prompt" gorm:"type:text"`
+	KeyStatus          *string `json:"key_status" gorm:"type:text"` // status of the disabled keys of a multi-key channel, by key fingerprint
 }
 
 type ChannelConfig struct {
@@ -50,6 +59,29 @@ type ChannelConfig struct {
 	Plugin            string `json:"plugin,omitempty"`
 	VertexAIProjectID string `json:"vertex_ai_project_id,omitempty"`
 	VertexAIADC       string `json:"vertex_ai_adc,omitempty"`
+	// MultiKey makes the channel hold one key per line instead of a single key
+	MultiKey     bool   `json:"multi_key,omitempty"`
+	KeySelection string `json:"key_selection,omitempty"` // round_robin (default) or random
+	// ParamOverrides rewrite the request body sent upstream, in order
+	ParamOverrides []ParamOverride `json:"param_overrides,omitempty"`
+	// Headers are set on the upstream requests, over the ones set by the adaptor. The values
+	// may refer to {user_id}, {token_id}, {token_name}, {request_id} and {model}.
+	Headers map[string]string `json:"headers,omitempty"`
+	// ForwardHeaders are the client headers passed through to the upstream, e.g. anthropic-beta
+	ForwardHeaders []string `json:"forward_headers,omitempty"`
+	// FirstTokenTimeout and IdleTimeout override RELAY_FIRST_TOKEN_TIMEOUT and RELAY_IDLE_TIMEOUT, in seconds
+	FirstTokenTimeout int `json:"first_token_timeout,omitempty"`
+	IdleTimeout       int `json:"idle_timeout,omitempty"`
+}
+
+// ParamOverride rewrites fields of the request body sent to the channel. Fields are named by
+// their JSON path in the body, with nested objects separated by dots, e.g. "generationConfig.temperature".
+type ParamOverride struct {
+	Models   []string          `json:"models,omitempty"`   // the actual models it applies to, all when empty
+	Rename   map[string]string `json:"rename,omitempty"`   // moves a field to another name
+	Delete   []string          `json:"delete,omitempty"`   // drops fields the upstream rejects
+	Defaults map[string]any    `json:"defaults,omitempty"` // sets fields the client left out
+	Force    map[string]any    `json:"force,omitempty"`    // sets fields whatever the client sent
 }
 
 func GetAllChannels(startIdx int, num int, scope string) ([]*Channel, error) {
@@ -104,6 +136,13 @@ func (channel *Channel) GetPriority() int64 {
 	return *channel.Priority
 }
 
+func (channel *Channel) GetWeight() uint {
+	if channel.Weight == nil {
+		return 0
+	}
+	return *channel.Weight
+}
+
 func (channel *Channel) GetBaseURL() string {
 	if channel.BaseURL == nil {
 		return ""
@@ -222,3 +261,216 @@ func DeleteDisabledChannel() (int64, error) {
 	result := DB.Where("status = ? or status = ?", ChannelStatusAutoDisabled, ChannelStatusManuallyDisabled).Delete(&Channel{})
 	return result.RowsAffected, result.Error
 }
+
+const (
+	KeySelectionRoundRobin = "round_robin"
+	KeySelectionRandom     = "random"
+)
+
+type ChannelKeyStatus struct {
+	Status       int    `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+	DisabledTime int64  `json:"disabled_time,omitempty"`
+}
+
+// ChannelKeyHealth describes one key of a channel for the admin API, without revealing it.
+type ChannelKeyHealth struct {
+	Index        int    `json:"index"`
+	Fingerprint  string `json:"fingerprint"`
+	Key          string `json:"key"` // masked
+	Status       int    `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+	DisabledTime int64  `json:"disabled_time,omitempty"`
+}
+
+// disabledKeyRef identifies a key within its channel, the same key may serve several channels.
+type disabledKeyRef struct {
+	channelId   int
+	fingerprint string
+}
+
+// keys disabled by this instance since the channel cache was last synced, the key status saved
+// in the database takes over once it is
+var disabledKeys sync.Map
+
+// forgetDisabledKeys is called when the channel cache is synced from the database.
+func forgetDisabledKeys() {
+	disabledKeys.Range(func(ref, _ any) bool {
+		disabledKeys.Delete(ref)
+		return true
+	})
+}
+
+// serializes the rewrites of the key status within this instance
+var keyStatusLock sync.Mutex
+
+// round-robin cursors by channel id
+var keyCursors sync.Map
+
+// KeyFingerprint identifies a key in the key status and the admin API.
+func KeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + "..." + key[len(key)-4:]
+}
+
+// GetKeys returns the keys of the channel, a channel holds a single key unless multi_key is set.
+func (channel *Channel) GetKeys() []string {
+	cfg, _ := channel.LoadConfig()
+	if !cfg.MultiKey {
+		return []string{channel.Key}
+	}
+	var keys []string
+	for _, key := range strings.Split(channel.Key, "\n") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (channel *Channel) GetKeyStatus() map[string]ChannelKeyStatus {
+	keyStatus := make(map[string]ChannelKeyStatus)
+	if channel.KeyStatus == nil || *channel.KeyStatus == "" {
+		return keyStatus
+	}
+	err := json.Unmarshal([]byte(*channel.KeyStatus), &keyStatus)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to unmarshal key status for channel %d, error: %s", channel.Id, err.Error()))
+	}
+	return keyStatus
+}
+
+func (channel *Channel) isKeyEnabled(keyStatus map[string]ChannelKeyStatus, fingerprint string) bool {
+	if _, ok := disabledKeys.Load(disabledKeyRef{channelId: channel.Id, fingerprint: fingerprint}); ok {
+		return false
+	}
+	status, ok := keyStatus[fingerprint]
+	return !ok || status.Status == ChannelStatusEnabled
+}
+
+// PickKey selects the key to use for a request, skipping disabled keys.
+func (channel *Channel) PickKey() string {
+	keys := channel.GetKeys()
+	if len(keys) <= 1 {
+		return channel.Key
+	}
+	keyStatus := channel.GetKeyStatus()
+	enabledKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if channel.isKeyEnabled(keyStatus, KeyFingerprint(key)) {
+			enabledKeys = append(enabledKeys, key)
+		}
+	}
+	if len(enabledKeys) == 0 {
+		// the channel is about to be disabled, keep trying its keys meanwhile
+		enabledKeys = keys
+	}
+	cfg, _ := channel.LoadConfig()
+	if cfg.KeySelection == KeySelectionRandom {
+		return enabledKeys[rand.Intn(len(enabledKeys))]
+	}
+	cursor, _ := keyCursors.LoadOrStore(channel.Id, new(uint64))
+	next := atomic.AddUint64(cursor.(*uint64), 1)
+	return enabledKeys[next%uint64(len(enabledKeys))]
+}
+
+// GetKeyHealth lists the keys of the channel with their status.
+func (channel *Channel) GetKeyHealth() []ChannelKeyHealth {
+	keyStatus := channel.GetKeyStatus()
+	keys := channel.GetKeys()
+	health := make([]ChannelKeyHealth, 0, len(keys))
+	for i, key := range keys {
+		fingerprint := KeyFingerprint(key)
+		status := keyStatus[fingerprint]
+		if status.Status == ChannelStatusUnknown {
+			status.Status = ChannelStatusEnabled
+		}
+		health = append(health, ChannelKeyHealth{
+			Index:        i,
+			Fingerprint:  fingerprint,
+			Key:          maskKey(key),
+			Status:       status.Status,
+			Reason:       status.Reason,
+			DisabledTime: status.DisabledTime,
+		})
+	}
+	return health
+}
+
+// UpdateChannelKeyStatus sets the status of one key of a multi-key channel, and reports
+// whether the channel has any enabled key left.
+func UpdateChannelKeyStatus(id int, fingerprint string, status int, reason string) (hasEnabledKey bool, err error) {
+	// keys are disabled concurrently by the relay, the key status is locked while it is rewritten:
+	// in the database for other instances, and here for SQLite which has no row locks
+	keyStatusLock.Lock()
+	defer keyStatusLock.Unlock()
+	channel := &Channel{}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(channel, "id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, key := range channel.GetKeys() {
+			if KeyFingerprint(key) == fingerprint {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("key not found")
+		}
+		keyStatus := channel.GetKeyStatus()
+		if status == ChannelStatusEnabled {
+			delete(keyStatus, fingerprint)
+		} else {
+			keyStatus[fingerprint] = ChannelKeyStatus{
+				Status:       status,
+				Reason:       reason,
+				DisabledTime: helper.GetTimestamp(),
+			}
+		}
+		// forget the status of keys which were removed from the channel
+		fingerprints := make(map[string]bool)
+		for _, key := range channel.GetKeys() {
+			fingerprints[KeyFingerprint(key)] = true
+		}
+		for fingerprint := range keyStatus {
+			if !fingerprints[fingerprint] {
+				delete(keyStatus, fingerprint)
+			}
+		}
+		jsonBytes, err := json.Marshal(keyStatus)
+		if err != nil {
+			return err
+		}
+		keyStatusStr := string(jsonBytes)
+		channel.KeyStatus = &keyStatusStr
+		return tx.Model(&Channel{}).Where("id = ?", id).Update("key_status", keyStatusStr).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	ref := disabledKeyRef{channelId: id, fingerprint: fingerprint}
+	if status == ChannelStatusEnabled {
+		disabledKeys.Delete(ref)
+	} else if config.MemoryCacheEnabled {
+		// channels are read from the database otherwise, with the key status up to date
+		disabledKeys.Store(ref, struct{}{})
+	}
+	keyStatus := channel.GetKeyStatus()
+	for _, key := range channel.GetKeys() {
+		if channel.isKeyEnabled(keyStatus, KeyFingerprint(key)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestChannelPickKey(t *testing.T) {
+	channel := &Channel{Id: 1, Key: "sk-a\nsk-b\n\nsk-c\n", Config: `{"multi_key": true}`}
+	assert.Equal(t, []string{"sk-a", "sk-b", "sk-c"}, channel.GetKeys())
+
+	keyStatus, _ := json.Marshal(map[string]ChannelKeyStatus{
+		KeyFingerprint("sk-b"): {Status: ChannelStatusAutoDisabled, Reason: "invalid api key"},
+	})
+	keyStatusStr := string(keyStatus)
+	channel.KeyStatus = &keyStatusStr
+
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		counts[channel.PickKey()]++
+	}
+	assert.Equal(t, map[string]int{"sk-a": 5, "sk-c": 5}, counts)
+
+	health := channel.GetKeyHealth()
+	assert.Len(t, health, 3)
+	assert.Equal(t, ChannelStatusAutoDisabled, health[1].Status)
+	assert.Equal(t, ChannelStatusEnabled, health[2].Status)
+	assert.NotContains(t, health[0].Key, "sk-a")
+
+	// keys disabled before the cache is synced are only skipped on their own channel
+	disabledKeys.Store(disabledKeyRef{channelId: 1, fingerprint: KeyFingerprint("sk-a")}, struct{}{})
+	defer forgetDisabledKeys()
+	assert.Equal(t, "sk-c", channel.PickKey())
+	other := &Channel{Id: 3, Key: "sk-a\nsk-d", Config: `{"multi_key": true}`}
+	assert.ElementsMatch(t, []string{"sk-a", "sk-d"}, []string{other.PickKey(), other.PickKey()})
+	forgetDisabledKeys()
+	assert.Contains(t, []string{"sk-a", "sk-c"}, channel.PickKey())
+
+	// without multi_key the key is used as a whole
+	single := &Channel{Id: 2, Key: "sk-a\nsk-b"}
+	assert.Equal(t, "sk-a\nsk-b", single.PickKey())
+}
+
+func TestUpdateChannelKeyStatusConcurrently(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// every connection would open another database
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&Channel{}))
+	saved := DB
+	DB = db
+	defer func() { DB = saved }()
+	defer forgetDisabledKeys()
+
+	keys := []string{"sk-a", "sk-b", "sk-c", "sk-d"}
+	require.NoError(t, DB.Create(&Channel{Id: 1, Key: "sk-a\nsk-b\nsk-c\nsk-d", Config: `{"multi_key": true}`}).Error)
+	var wg sync.WaitGroup
+	for _, key := range keys[:3] {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, err := UpdateChannelKeyStatus(1, KeyFingerprint(key), ChannelStatusAutoDisabled, "invalid api key")
+			assert.NoError(t, err)
+		}(key)
+	}
+	wg.Wait()
+
+	// no update was lost
+	channel, err := GetChannelById(1, true)
+	require.NoError(t, err)
+	keyStatus := channel.GetKeyStatus()
+	assert.Len(t, keyStatus, 3)
+	assert.NotContains(t, keyStatus, KeyFingerprint("sk-d"))
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+const (
+	FileStatusProcessed = "processed"
+)
+
+// File is a file uploaded through the Files API. Its content lives in common/storage under StorageKey.
+type File struct {
+	Id          int    `json:"id"`
+	FileId      string `json:"file_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	TokenId     int    `json:"token_id"`
+	Filename    string `json:"filename"`
+	Purpose     string `json:"purpose" gorm:"type:varchar(32);index"`
+	Bytes       int64  `json:"bytes" gorm:"bigint"`
+	ContentType string `json:"content_type"`
+	StorageKey  string `json:"-"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	// UpstreamFiles is a JSON map of "channel id:key fingerprint" to the id of the copy mirrored
+	// to that channel with that key
+	UpstreamFiles *string `json:"-" gorm:"type:text"`
+}
+
+func (file *File) Insert() error {
+	return DB.Create(file).Error
+}
+
+func (file *File) Delete() error {
+	return DB.Delete(file).Error
+}
+
+func GetFileByFileId(fileId string, userId int) (*File, error) {
+	if fileId == "" || userId == 0 {
+		return nil, errors.New("file id 或 user id 为空！")
+	}
+	file := File{}
+	err := DB.Where("file_id = ? and user_id = ?", fileId, userId).First(&file).Error
+	return &file, err
+}
+
+// GetUserFiles lists the files of a user, newest first. A non-empty purpose filters them,
+// and after is the file id the previous page ended with.
+func GetUserFiles(userId int, purpose string, after string, limit int) ([]*File, error) {
+	var files []*File
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	if after != "" {
+		afterFile, err := GetFileByFileId(after, userId)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id < ?", afterFile.Id)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+func (file *File) getUpstreamFiles() map[string]string {
+	upstreamFiles := make(map[string]string)
+	if file.UpstreamFiles == nil || *file.UpstreamFiles == "" {
+		return upstreamFiles
+	}
+	err := json.Unmarshal([]byte(*file.UpstreamFiles), &upstreamFiles)
+	if err != nil {
+		logger.SysError("failed to unmarshal upstream files: " + err.Error())
+	}
+	return upstreamFiles
+}
+
+// upstreamFileKey identifies a mirrored copy: files belong to the account of the key they
+// were uploaded with, and the keys of a channel may belong to different accounts.
+func upstreamFileKey(channelId int, keyFingerprint string) string {
+	return fmt.Sprintf("%d:%s", channelId, keyFingerprint)
+}
+
+// UpstreamFile is a copy of a file mirrored to a channel with one of its keys.
+type UpstreamFile struct {
+	ChannelId      int
+	KeyFingerprint string
+	FileId         string
+}
+
+// GetUpstreamFileId returns the id of the copy mirrored to the channel with the key, if any.
+func (file *File) GetUpstreamFileId(channelId int, keyFingerprint string) string {
+	return file.getUpstreamFiles()[upstreamFileKey(channelId, keyFingerprint)]
+}
+
+// GetUpstreamFileIds returns all mirrored copies.
+func (file *File) GetUpstreamFileIds() []UpstreamFile {
+	var upstreamFiles []UpstreamFile
+	for key, upstreamFileId := range file.getUpstreamFiles() {
+		channelId, keyFingerprint, _ := strings.Cut(key, ":")
+		id, err := strconv.Atoi(channelId)
+		if err != nil {
+			continue
+		}
+		upstreamFiles = append(upstreamFiles, UpstreamFile{ChannelId: id, KeyFingerprint: keyFingerprint, FileId: upstreamFileId})
+	}
+	return upstreamFiles
+}
+
+func (file *File) SetUpstreamFileId(channelId int, keyFingerprint string, upstreamFileId string) error {
+	upstreamFiles := file.getUpstreamFiles()
+	upstreamFiles[upstreamFileKey(channelId, keyFingerprint)] = upstreamFileId
+	jsonBytes, err := json.Marshal(upstreamFiles)
+	if err != nil {
+		return err
+	}
+	upstreamFilesStr := string(jsonBytes)
+	file.UpstreamFiles = &upstreamFilesStr
+	return DB.Model(file).Update("upstream_files", upstreamFilesStr).Error
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileUpstreamFileIds(t *testing.T) {
+	upstreamFiles := `{"3:aaaa":"file-a","3:bbbb":"file-b"}`
+	file := &File{UpstreamFiles: &upstreamFiles}
+	// each key of a channel has its own copy
+	assert.Equal(t, "file-a", file.GetUpstreamFileId(3, "aaaa"))
+	assert.Equal(t, "file-b", file.GetUpstreamFileId(3, "bbbb"))
+	assert.Empty(t, file.GetUpstreamFileId(4, "aaaa"))
+	assert.ElementsMatch(t, []UpstreamFile{
+		{ChannelId: 3, KeyFingerprint: "aaaa", FileId: "file-a"},
+		{ChannelId: 3, KeyFingerprint: "bbbb", FileId: "file-b"},
+	}, file.GetUpstreamFileIds())
+}
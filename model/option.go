@@ -3,7 +3,10 @@ package model
 import (
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/routing"
 	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
+	"github.com/LeXwDeX/one-api/relay/responsecache"
 	"strconv"
 	"strings"
 	"time"
@@ -69,10 +72,20 @@ func InitOptionMap() {
 	config.OptionMap["PreConsumedQuota"] = strconv.FormatInt(config.PreConsumedQuota, 10)
 	config.OptionMap["ModelRatio"] = billingratio.ModelRatio2JSONString()
 	config.OptionMap["GroupRatio"] = billingratio.GroupRatio2JSONString()
+	config.OptionMap["GroupRateLimit"] = ratelimit.GroupRateLimit2JSONString()
+	config.OptionMap["ResponseCacheGroups"] = responsecache.Groups2JSONString()
+	config.OptionMap["RoutingStrategy"] = routing.RoutingStrategy2JSONString()
+	config.OptionMap["ModelFallback"] = routing.ModelFallback2JSONString()
 	config.OptionMap["CompletionRatio"] = billingratio.CompletionRatio2JSONString()
+	config.OptionMap["CacheReadRatio"] = billingratio.CacheReadRatio2JSONString()
+	config.OptionMap["CacheWriteRatio"] = billingratio.CacheWriteRatio2JSONString()
+	config.OptionMap["AudioPromptRatio"] = billingratio.AudioPromptRatio2JSONString()
+	config.OptionMap["AudioCompletionRatio"] = billingratio.AudioCompletionRatio2JSONString()
 	config.OptionMap["TopUpLink"] = config.TopUpLink
 	config.OptionMap["ChatLink"] = config.ChatLink
 	config.OptionMap["QuotaPerUnit"] = strconv.FormatFloat(config.QuotaPerUnit, 'f', -1, 64)
+	config.OptionMap["BatchRatio"] = strconv.FormatFloat(config.BatchRatio, 'f', -1, 64)
+	config.OptionMap["ResponseCacheHitRatio"] = strconv.FormatFloat(config.ResponseCacheHitRatio, 'f', -1, 64)
 	config.OptionMap["RetryTimes"] = strconv.Itoa(config.RetryTimes)
 	config.OptionMap["Theme"] = config.Theme
 	config.OptionMapRWMutex.Unlock()
@@ -227,8 +240,24 @@ func updateOptionMap(key string, value string) (err error) {
 		err = billingratio.UpdateModelRatioByJSONString(value)
 	case "GroupRatio":
 		err = billingratio.UpdateGroupRatioByJSONString(value)
+	case "GroupRateLimit":
+		err = ratelimit.UpdateGroupRateLimitByJSONString(value)
+	case "ResponseCacheGroups":
+		err = responsecache.UpdateGroupsByJSONString(value)
+	case "RoutingStrategy":
+		err = routing.UpdateRoutingStrategyByJSONString(value)
+	case "ModelFallback":
+		err = routing.UpdateModelFallbackByJSONString(value)
 	case "CompletionRatio":
 		err = billingratio.UpdateCompletionRatioByJSONString(value)
+	case "CacheReadRatio":
+		err = billingratio.UpdateCacheReadRatioByJSONString(value)
+	case "CacheWriteRatio":
+		err = billingratio.UpdateCacheWriteRatioByJSONString(value)
+	case "AudioPromptRatio":
+		err = billingratio.UpdateAudioPromptRatioByJSONString(value)
+	case "AudioCompletionRatio":
+		err = billingratio.UpdateAudioCompletionRatioByJSONString(value)
 	case "TopUpLink":
 		config.TopUpLink = value
 	case "ChatLink":
@@ -237,6 +266,10 @@ func updateOptionMap(key string, value string) (err error) {
 		config.ChannelDisableThreshold, _ = strconv.ParseFloat(value, 64)
 	case "QuotaPerUnit":
 		config.QuotaPerUnit, _ = strconv.ParseFloat(value, 64)
+	case "BatchRatio":
+		config.BatchRatio, _ = strconv.ParseFloat(value, 64)
+	case "ResponseCacheHitRatio":
+		config.ResponseCacheHitRatio, _ = strconv.ParseFloat(value, 64)
 	case "Theme":
 		config.Theme = value
 	}
@@ -34,6 +34,10 @@ type Token struct {
 	UsedQuota      int64   `json:"used_quota" gorm:"bigint;default:0"` // used quota
 	Models         *string `json:"models" gorm:"type:text"`            // allowed models
 	Subnet         *string `json:"subnet" gorm:"default:''"`           // allowed subnet
+	RpmLimit       int     `json:"rpm_limit" gorm:"default:0"`         // requests per minute, 0 means unlimited
+	TpmLimit       int     `json:"tpm_limit" gorm:"default:0"`         // tokens per minute, 0 means unlimited
+	// ResponseCache reuses the responses to deterministic requests, see relay/responsecache
+	ResponseCache bool `json:"response_cache" gorm:"default:false"`
 }
 
 func GetAllUserTokens(userId int, startIdx int, num int, order string) ([]*Token, error) {
@@ -132,7 +136,7 @@ func (t *Token) Insert() error {
 // Update Make sure your token's fields is completed, because this will update non-zero values
 func (t *Token) Update() error {
 	var err error
-	err = DB.Model(t).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "models", "subnet").Updates(t).Error
+	err = DB.Model(t).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "models", "subnet", "rpm_limit", "tpm_limit", "response_cache").Updates(t).Error
 	return err
 }
 
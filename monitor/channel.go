@@ -44,6 +44,20 @@ func DisableChannel(channelId int, channelName string, reason string) {
 	notifyRootUser(subject, content)
 }
 
+// DisableChannelKey disables one key of a multi-key channel, and the channel once no key is left
+func DisableChannelKey(channelId int, channelName string, fingerprint string, reason string) {
+	hasEnabledKey, err := model.UpdateChannelKeyStatus(channelId, fingerprint, model.ChannelStatusAutoDisabled, reason)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to disable key %s of channel #%d: %s", fingerprint, channelId, err.Error()))
+		DisableChannel(channelId, channelName, reason)
+		return
+	}
+	logger.SysLog(fmt.Sprintf("key %s of channel #%d has been disabled: %s", fingerprint, channelId, reason))
+	if !hasEnabledKey {
+		DisableChannel(channelId, channelName, "所有密钥均已被禁用，最后一次错误："+reason)
+	}
+}
+
 func MetricDisableChannel(channelId int, successRate float64) {
 	model.UpdateChannelStatusById(channelId, model.ChannelStatusAutoDisabled)
 	logger.SysLog(fmt.Sprintf("channel #%d has been disabled due to low success rate: %.2f", channelId, successRate*100))
@@ -0,0 +1,414 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LeXwDeX/one-api/common/conv"
+	"github.com/LeXwDeX/one-api/common/random"
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// The functions below serve the /v1/messages ingress: clients talk Anthropic to One API,
+// while the selected channel may be of any type.
+
+func parseIngressContent(content any) ([]IngressContent, error) {
+	switch content := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []IngressContent{{Type: "text", Text: content}}, nil
+	}
+	jsonData, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var contents []IngressContent
+	err = json.Unmarshal(jsonData, &contents)
+	return contents, err
+}
+
+func ingressContentText(content any) string {
+	contents, err := parseIngressContent(content)
+	if err != nil {
+		return conv.AsString(content)
+	}
+	var texts []string
+	for _, part := range contents {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func stopReasonOpenAI2Claude(reason string) string {
+	switch reason {
+	case "stop", "":
+		return "end_turn"
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	default:
+		return reason
+	}
+}
+
+// ConvertIngressRequest translates a Messages API request into a chat completions request.
+func ConvertIngressRequest(request *IngressRequest) (*model.GeneralOpenAIRequest, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	textRequest := &model.GeneralOpenAIRequest{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		TopK:        request.TopK,
+		Stream:      request.Stream,
+	}
+	if len(request.StopSequences) > 0 {
+		textRequest.Stop = request.StopSequences
+	}
+	if request.Metadata != nil {
+		textRequest.User = request.Metadata.UserId
+	}
+	for _, tool := range request.Tools {
+		textRequest.Tools = append(textRequest.Tools, model.Tool{
+			Type: "function",
+			Function: model.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	if request.ToolChoice != nil {
+		switch request.ToolChoice.Type {
+		case "auto":
+			textRequest.ToolChoice = "auto"
+		case "any":
+			textRequest.ToolChoice = "required"
+		case "none":
+			textRequest.ToolChoice = "none"
+		case "tool":
+			textRequest.ToolChoice = map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name": request.ToolChoice.Name,
+				},
+			}
+		}
+	}
+	if system := ingressContentText(request.System); system != "" {
+		textRequest.Messages = append(textRequest.Messages, model.Message{
+			Role:    role.System,
+			Content: system,
+		})
+	}
+	for _, message := range request.Messages {
+		contents, err := parseIngressContent(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content of %s message: %w", message.Role, err)
+		}
+		if message.Role == role.Assistant {
+			textRequest.Messages = append(textRequest.Messages, convertIngressAssistantMessage(contents))
+			continue
+		}
+		var parts []any
+		for _, content := range contents {
+			switch content.Type {
+			case "text":
+				parts = append(parts, map[string]any{
+					"type": model.ContentTypeText,
+					"text": content.Text,
+				})
+			case "image":
+				if content.Source == nil {
+					continue
+				}
+				url := content.Source.Url
+				if content.Source.Type == "base64" {
+					url = fmt.Sprintf("data:%s;base64,%s", content.Source.MediaType, content.Source.Data)
+				}
+				parts = append(parts, map[string]any{
+					"type": model.ContentTypeImageURL,
+					"image_url": map[string]any{
+						"url": url,
+					},
+				})
+			case "tool_result":
+				// tool results must directly follow the assistant's tool calls
+				textRequest.Messages = append(textRequest.Messages, model.Message{
+					Role:       role.Tool,
+					Content:    ingressContentText(content.Content),
+					ToolCallId: content.ToolUseId,
+				})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		textRequest.Messages = append(textRequest.Messages, model.Message{
+			Role:    message.Role,
+			Content: parts,
+		})
+	}
+	return textRequest, nil
+}
+
+func convertIngressAssistantMessage(contents []IngressContent) model.Message {
+	message := model.Message{
+		Role: role.Assistant,
+	}
+	var text string
+	for _, content := range contents {
+		switch content.Type {
+		case "text":
+			text += content.Text
+		case "tool_use":
+			arguments, _ := json.Marshal(content.Input)
+			message.ToolCalls = append(message.ToolCalls, model.Tool{
+				Id:   content.Id,
+				Type: "function",
+				Function: model.Function{
+					Name:      content.Name,
+					Arguments: string(arguments),
+				},
+			})
+		}
+	}
+	message.Content = text
+	return message
+}
+
+func parseToolArguments(arguments any) any {
+	input := make(map[string]any)
+	if argumentsText := conv.AsString(arguments); argumentsText != "" {
+		_ = json.Unmarshal([]byte(argumentsText), &input)
+	}
+	return input
+}
+
+// usageOpenAI2Claude splits the cached tokens out of the prompt tokens, as input_tokens
+// of the Messages API does not include them.
+func usageOpenAI2Claude(usage *model.Usage) Usage {
+	cachedTokens := usage.GetCachedTokens()
+	cacheWriteTokens := usage.GetCacheWriteTokens()
+	return Usage{
+		InputTokens:              usage.PromptTokens - cachedTokens - cacheWriteTokens,
+		OutputTokens:             usage.CompletionTokens,
+		CacheCreationInputTokens: cacheWriteTokens,
+		CacheReadInputTokens:     cachedTokens,
+	}
+}
+
+// ResponseOpenAI2Claude converts a chat completions response into a Messages API response.
+func ResponseOpenAI2Claude(textResponse *openai.TextResponse) *Response {
+	response := Response{
+		Id:      fmt.Sprintf("msg_%s", random.GetUUID()),
+		Type:    "message",
+		Role:    role.Assistant,
+		Model:   textResponse.Model,
+		Usage:   usageOpenAI2Claude(&textResponse.Usage),
+		Content: make([]Content, 0),
+	}
+	var finishReason string
+	for _, choice := range textResponse.Choices {
+		if finishReason == "" {
+			finishReason = choice.FinishReason
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			response.Content = append(response.Content, Content{Type: "text", Text: text})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			response.Content = append(response.Content, Content{
+				Type:  "tool_use",
+				Id:    toolCall.Id,
+				Name:  toolCall.Function.Name,
+				Input: parseToolArguments(toolCall.Function.Arguments),
+			})
+		}
+	}
+	stopReason := stopReasonOpenAI2Claude(finishReason)
+	response.StopReason = &stopReason
+	return &response
+}
+
+// ErrorResponseOpenAI2Claude wraps a relay error the way the Messages API reports errors.
+func ErrorResponseOpenAI2Claude(err *model.ErrorWithStatusCode) *ErrorResponse {
+	errorType := "api_error"
+	switch err.StatusCode {
+	case http.StatusBadRequest:
+		errorType = "invalid_request_error"
+	case http.StatusUnauthorized:
+		errorType = "authentication_error"
+	case http.StatusForbidden:
+		errorType = "permission_error"
+	case http.StatusNotFound:
+		errorType = "not_found_error"
+	case http.StatusTooManyRequests:
+		errorType = "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		errorType = "overloaded_error"
+	}
+	return &ErrorResponse{
+		Type: "error",
+		Error: Error{
+			Type:    errorType,
+			Message: err.Message,
+		},
+	}
+}
+
+// IngressStreamEncoder re-encodes a chat completions stream as Messages API events.
+type IngressStreamEncoder struct {
+	meta         *meta.Meta
+	id           string
+	modelName    string
+	started      bool
+	blockIndex   int
+	blockType    string // type of the open content block, "" if none
+	toolCalls    map[int]int
+	finishReason string
+	usage        *model.Usage
+}
+
+// NewIngressStreamEncoder creates an encoder for one stream; the meta is only read
+// once the first chunk arrives, by which time the prompt tokens have been counted.
+func NewIngressStreamEncoder(meta *meta.Meta, modelName string) *IngressStreamEncoder {
+	return &IngressStreamEncoder{
+		meta:       meta,
+		id:         fmt.Sprintf("msg_%s", random.GetUUID()),
+		modelName:  modelName,
+		blockIndex: -1,
+		toolCalls:  make(map[int]int),
+	}
+}
+
+func (e *IngressStreamEncoder) start() []render.Event {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	message := &Response{
+		Id:      e.id,
+		Type:    "message",
+		Role:    role.Assistant,
+		Model:   e.modelName,
+		Content: make([]Content, 0),
+		Usage: Usage{
+			InputTokens: e.meta.PromptTokens,
+		},
+	}
+	return []render.Event{
+		{Event: "message_start", Data: StreamEvent{Type: "message_start", Message: message}},
+		{Event: "ping", Data: StreamEvent{Type: "ping"}},
+	}
+}
+
+func (e *IngressStreamEncoder) closeBlock() []render.Event {
+	if e.blockType == "" {
+		return nil
+	}
+	e.blockType = ""
+	index := e.blockIndex
+	return []render.Event{{Event: "content_block_stop", Data: StreamEvent{Type: "content_block_stop", Index: &index}}}
+}
+
+func (e *IngressStreamEncoder) openBlock(block map[string]any) []render.Event {
+	events := e.closeBlock()
+	e.blockIndex++
+	e.blockType = block["type"].(string)
+	index := e.blockIndex
+	return append(events, render.Event{Event: "content_block_start", Data: StreamEvent{Type: "content_block_start", Index: &index, ContentBlock: block}})
+}
+
+func (e *IngressStreamEncoder) delta(delta map[string]any) render.Event {
+	index := e.blockIndex
+	return render.Event{Event: "content_block_delta", Data: StreamEvent{Type: "content_block_delta", Index: &index, Delta: delta}}
+}
+
+func (e *IngressStreamEncoder) EncodeStreamChunk(chunk *openai.ChatCompletionsStreamResponse) []render.Event {
+	events := e.start()
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			e.finishReason = *choice.FinishReason
+		}
+		if text := conv.AsString(choice.Delta.Content); text != "" {
+			if e.blockType != "text" {
+				events = append(events, e.openBlock(map[string]any{"type": "text", "text": ""})...)
+			}
+			events = append(events, e.delta(map[string]any{"type": "text_delta", "text": text}))
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			isNewCall := toolCall.Id != "" || e.blockType != "tool_use"
+			if toolCall.Index != nil {
+				blockIndex, ok := e.toolCalls[*toolCall.Index]
+				isNewCall = !ok
+				if ok && blockIndex != e.blockIndex {
+					// deltas of an already closed block cannot be sent anymore
+					continue
+				}
+			}
+			if isNewCall {
+				events = append(events, e.openBlock(map[string]any{
+					"type":  "tool_use",
+					"id":    toolCall.Id,
+					"name":  toolCall.Function.Name,
+					"input": map[string]any{},
+				})...)
+				if toolCall.Index != nil {
+					e.toolCalls[*toolCall.Index] = e.blockIndex
+				}
+			}
+			if arguments := conv.AsString(toolCall.Function.Arguments); arguments != "" {
+				events = append(events, e.delta(map[string]any{"type": "input_json_delta", "partial_json": arguments}))
+			}
+		}
+	}
+	return events
+}
+
+func (e *IngressStreamEncoder) EncodeStreamEnd(usage *model.Usage) []render.Event {
+	events := e.start()
+	events = append(events, e.closeBlock()...)
+	if usage == nil {
+		usage = e.usage
+	}
+	var claudeUsage Usage
+	if usage != nil {
+		claudeUsage = usageOpenAI2Claude(usage)
+	}
+	events = append(events,
+		render.Event{Event: "message_delta", Data: StreamEvent{
+			Type: "message_delta",
+			Delta: map[string]any{
+				"stop_reason":   stopReasonOpenAI2Claude(e.finishReason),
+				"stop_sequence": nil,
+			},
+			Usage: &claudeUsage,
+		}},
+		render.Event{Event: "message_stop", Data: StreamEvent{Type: "message_stop"}},
+	)
+	return events
+}
+
+func (e *IngressStreamEncoder) EncodeResponse(textResponse *openai.TextResponse) any {
+	response := ResponseOpenAI2Claude(textResponse)
+	if response.Model == "" {
+		response.Model = e.modelName
+	}
+	return response
+}
@@ -0,0 +1,111 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertIngressRequest(t *testing.T) {
+	body := `{
+		"model": "claude-3-5-sonnet",
+		"max_tokens": 128,
+		"system": [{"type": "text", "text": "be brief"}],
+		"stop_sequences": ["END"],
+		"messages": [
+			{"role": "user", "content": "weather?"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "checking"},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "sunny"}]},
+				{"type": "text", "text": "thanks"}
+			]}
+		],
+		"tools": [{"name": "get_weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"}
+	}`
+	var request IngressRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	textRequest, err := ConvertIngressRequest(&request)
+	require.NoError(t, err)
+	assert.Equal(t, 128, textRequest.MaxTokens)
+	assert.Equal(t, "required", textRequest.ToolChoice)
+	require.Len(t, textRequest.Tools, 1)
+	assert.Equal(t, "get_weather", textRequest.Tools[0].Function.Name)
+
+	require.Len(t, textRequest.Messages, 5)
+	assert.Equal(t, role.System, textRequest.Messages[0].Role)
+	assert.Equal(t, "be brief", textRequest.Messages[0].StringContent())
+	assert.Equal(t, "weather?", textRequest.Messages[1].StringContent())
+	assert.Equal(t, `{"city":"Paris"}`, textRequest.Messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, role.Tool, textRequest.Messages[3].Role)
+	assert.Equal(t, "toolu_1", textRequest.Messages[3].ToolCallId)
+	assert.Equal(t, "sunny", textRequest.Messages[3].StringContent())
+	assert.Equal(t, "thanks", textRequest.Messages[4].StringContent())
+}
+
+func TestIngressStreamEncoder(t *testing.T) {
+	encoder := NewIngressStreamEncoder(&meta.Meta{PromptTokens: 3}, "claude-3-5-sonnet")
+	stop := "tool_calls"
+	index := 0
+	var events []string
+	for _, chunk := range []openai.ChatCompletionsStreamResponse{
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{Content: "Hel"}}}},
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{Content: "lo"}}}},
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Index: &index, Id: "call_1", Function: relaymodel.Function{Name: "f"}}}}}}},
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Index: &index, Function: relaymodel.Function{Arguments: "{}"}}}}, FinishReason: &stop}}},
+	} {
+		for _, event := range encoder.EncodeStreamChunk(&chunk) {
+			events = append(events, event.Event)
+		}
+	}
+	end := encoder.EncodeStreamEnd(&relaymodel.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	for _, event := range end {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"ping",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_delta",
+		"content_block_stop",
+		"content_block_start",
+		"content_block_delta",
+		"content_block_stop",
+		"message_delta",
+		"message_stop",
+	}, events)
+
+	messageDelta := end[len(end)-2].Data.(StreamEvent)
+	assert.Equal(t, "tool_use", messageDelta.Delta["stop_reason"])
+	assert.Equal(t, 2, messageDelta.Usage.OutputTokens)
+}
+
+func TestUsageCacheRoundTrip(t *testing.T) {
+	claudeUsage := Usage{
+		InputTokens:              10,
+		OutputTokens:             5,
+		CacheCreationInputTokens: 200,
+		CacheReadInputTokens:     1000,
+	}
+	usage := claudeUsage.ToUsage()
+	assert.Equal(t, 1210, usage.PromptTokens)
+	assert.Equal(t, 1215, usage.TotalTokens)
+	assert.Equal(t, 1000, usage.GetCachedTokens())
+	assert.Equal(t, 200, usage.GetCacheWriteTokens())
+	assert.Equal(t, claudeUsage, usageOpenAI2Claude(usage))
+
+	usage = (&Usage{InputTokens: 10, OutputTokens: 5}).ToUsage()
+	assert.Nil(t, usage.PromptTokensDetails)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/LeXwDeX/one-api/common/render"
 	"io"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +37,37 @@ func stopReasonClaude2OpenAI(reason *string) string {
 	}
 }
 
+func isForcedToolChoice(toolChoice any) bool {
+	if toolChoice == nil {
+		return false
+	}
+	var choice struct {
+		Type string `json:"type"`
+	}
+	jsonData, _ := json.Marshal(toolChoice)
+	_ = json.Unmarshal(jsonData, &choice)
+	return choice.Type == "tool" || choice.Type == "any"
+}
+
+// thinkingModelRegexp matches the Claude models with extended thinking: Claude 3.7 and the
+// sonnet, opus and haiku generations from 4 on, also under their Bedrock and Vertex AI names.
+var thinkingModelRegexp = regexp.MustCompile(`claude-3-7|claude-(?:sonnet|opus|haiku)-[4-9]`)
+
+// canThink tells whether thinking can be turned on for the request. Earlier turns calling
+// tools would have to be sent back with their signed thinking blocks, which OpenAI clients
+// don't keep, so thinking is left off for them.
+func canThink(textRequest model.GeneralOpenAIRequest) bool {
+	if !thinkingModelRegexp.MatchString(textRequest.Model) {
+		return false
+	}
+	for _, message := range textRequest.Messages {
+		if message.Role == "tool" || len(message.ToolCalls) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func ConvertRequest(textRequest model.GeneralOpenAIRequest) *Request {
 	claudeTools := make([]Tool, 0, len(textRequest.Tools))
 
@@ -44,11 +76,7 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *Request {
 			claudeTools = append(claudeTools, Tool{
 				Name:        tool.Function.Name,
 				Description: tool.Function.Description,
-				InputSchema: InputSchema{
-					Type:       params["type"].(string),
-					Properties: params["properties"],
-					Required:   params["required"],
-				},
+				InputSchema: toInputSchema(params),
 			})
 		}
 	}
@@ -79,9 +107,30 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *Request {
 		}
 		claudeRequest.ToolChoice = claudeToolChoice
 	}
+	if tool := getStructuredOutputTool(textRequest.ResponseFormat); tool != nil {
+		if len(claudeRequest.Tools) == 0 {
+			// nothing else to call, so the answer has to come as the structured output
+			claudeRequest.ToolChoice = map[string]string{"type": "tool", "name": tool.Name}
+		}
+		claudeRequest.Tools = append(claudeRequest.Tools, *tool)
+	}
 	if claudeRequest.MaxTokens == 0 {
 		claudeRequest.MaxTokens = 4096
 	}
+	if budget, ok := textRequest.GetThinkingBudget(); ok && budget > 0 && canThink(textRequest) {
+		claudeRequest.Thinking = &Thinking{Type: "enabled", BudgetTokens: budget}
+		if claudeRequest.MaxTokens <= budget {
+			// the budget is part of max_tokens, which is then left for the answer
+			claudeRequest.MaxTokens += budget
+		}
+		// thinking goes along with neither sampling parameters nor forced tool use
+		claudeRequest.Temperature = nil
+		claudeRequest.TopP = nil
+		claudeRequest.TopK = 0
+		if isForcedToolChoice(claudeRequest.ToolChoice) {
+			claudeRequest.ToolChoice = map[string]string{"type": "auto"}
+		}
+	}
 	// legacy model name mapping
 	if claudeRequest.Model == "claude-instant-1" {
 		claudeRequest.Model = "claude-instant-1.1"
@@ -149,6 +198,7 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *Request {
 func StreamResponseClaude2OpenAI(claudeResponse *StreamResponse) (*openai.ChatCompletionsStreamResponse, *Response) {
 	var response *Response
 	var responseText string
+	var reasoningText string
 	var stopReason string
 	tools := make([]model.Tool, 0)
 
@@ -172,6 +222,7 @@ func StreamResponseClaude2OpenAI(claudeResponse *StreamResponse) (*openai.ChatCo
 	case "content_block_delta":
 		if claudeResponse.Delta != nil {
 			responseText = claudeResponse.Delta.Text
+			reasoningText = claudeResponse.Delta.Thinking
 			if claudeResponse.Delta.Type == "input_json_delta" {
 				tools = append(tools, model.Tool{
 					Function: model.Function{
@@ -192,6 +243,9 @@ func StreamResponseClaude2OpenAI(claudeResponse *StreamResponse) (*openai.ChatCo
 	}
 	var choice openai.ChatCompletionsStreamResponseChoice
 	choice.Delta.Content = responseText
+	if reasoningText != "" {
+		choice.Delta.ReasoningContent = reasoningText
+	}
 	if len(tools) > 0 {
 		choice.Delta.Content = nil // compatible with other OpenAI derivative applications, like LobeOpenAICompatibleFactory ...
 		choice.Delta.ToolCalls = tools
@@ -209,11 +263,23 @@ func StreamResponseClaude2OpenAI(claudeResponse *StreamResponse) (*openai.ChatCo
 
 func ResponseClaude2OpenAI(claudeResponse *Response) *openai.TextResponse {
 	var responseText string
-	if len(claudeResponse.Content) > 0 {
-		responseText = claudeResponse.Content[0].Text
-	}
+	var reasoningText string
 	tools := make([]model.Tool, 0)
+	structuredOutput := false
 	for _, v := range claudeResponse.Content {
+		switch v.Type {
+		case "text":
+			responseText += v.Text
+			continue
+		case "thinking":
+			reasoningText += v.Thinking
+			continue
+		}
+		if v.Type == "tool_use" && v.Name == StructuredOutputToolName {
+			responseText = structuredOutputContent(v.Input)
+			structuredOutput = true
+			continue
+		}
 		if v.Type == "tool_use" {
 			args, _ := json.Marshal(v.Input)
 			tools = append(tools, model.Tool{
@@ -236,6 +302,12 @@ func ResponseClaude2OpenAI(claudeResponse *Response) *openai.TextResponse {
 		},
 		FinishReason: stopReasonClaude2OpenAI(claudeResponse.StopReason),
 	}
+	if reasoningText != "" {
+		choice.Message.ReasoningContent = reasoningText
+	}
+	if structuredOutput && len(tools) == 0 {
+		choice.FinishReason = "stop"
+	}
 	fullTextResponse := openai.TextResponse{
 		Id:      fmt.Sprintf("chatcmpl-%s", claudeResponse.Id),
 		Model:   claudeResponse.Model,
@@ -264,10 +336,12 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 
 	common.SetEventStreamHeaders(c)
 
-	var usage model.Usage
+	var claudeUsage Usage
 	var modelName string
 	var id string
 	var lastToolCallChoice openai.ChatCompletionsStreamResponseChoice
+	var structuredOutput StructuredOutputStream
+	var reasoningText strings.Builder
 
 	for scanner.Scan() {
 		data := scanner.Text()
@@ -285,9 +359,11 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		}
 
 		response, meta := StreamResponseClaude2OpenAI(&claudeResponse)
+		if response != nil {
+			structuredOutput.Unwrap(&claudeResponse, response)
+		}
 		if meta != nil {
-			usage.PromptTokens += meta.Usage.InputTokens
-			usage.CompletionTokens += meta.Usage.OutputTokens
+			claudeUsage.Add(&meta.Usage)
 			if len(meta.Id) > 0 { // only message_start has an id, otherwise it's a finish_reason event.
 				modelName = meta.Model
 				id = fmt.Sprintf("chatcmpl-%s", meta.Id)
@@ -310,6 +386,11 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		response.Id = id
 		response.Model = modelName
 		response.Created = createdTime
+		for _, choice := range response.Choices {
+			if reasoning, ok := choice.Delta.ReasoningContent.(string); ok {
+				reasoningText.WriteString(reasoning)
+			}
+		}
 
 		for _, choice := range response.Choices {
 			if len(choice.Delta.ToolCalls) > 0 {
@@ -332,7 +413,22 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 	if err != nil {
 		return openai.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
 	}
-	return nil, &usage
+	usage := claudeUsage.ToUsage()
+	setReasoningTokens(usage, reasoningText.String(), modelName)
+	return nil, usage
+}
+
+// setReasoningTokens tells how many of the completion tokens went to thinking, which Claude
+// bills as output without counting apart.
+func setReasoningTokens(usage *model.Usage, reasoningText string, modelName string) {
+	if reasoningText == "" {
+		return
+	}
+	reasoningTokens := openai.CountTokenText(reasoningText, modelName)
+	if reasoningTokens > usage.CompletionTokens {
+		reasoningTokens = usage.CompletionTokens
+	}
+	usage.CompletionTokensDetails = &model.CompletionTokensDetails{ReasoningTokens: reasoningTokens}
 }
 
 func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
@@ -349,7 +445,7 @@ func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName st
 	if err != nil {
 		return openai.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
 	}
-	if claudeResponse.Error.Type != "" {
+	if claudeResponse.Error != nil && claudeResponse.Error.Type != "" {
 		return &model.ErrorWithStatusCode{
 			Error: model.Error{
 				Message: claudeResponse.Error.Message,
@@ -362,12 +458,11 @@ func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName st
 	}
 	fullTextResponse := ResponseClaude2OpenAI(&claudeResponse)
 	fullTextResponse.Model = modelName
-	usage := model.Usage{
-		PromptTokens:     claudeResponse.Usage.InputTokens,
-		CompletionTokens: claudeResponse.Usage.OutputTokens,
-		TotalTokens:      claudeResponse.Usage.InputTokens + claudeResponse.Usage.OutputTokens,
+	usage := claudeResponse.Usage.ToUsage()
+	if reasoningText, ok := fullTextResponse.Choices[0].Message.ReasoningContent.(string); ok {
+		setReasoningTokens(usage, reasoningText, modelName)
 	}
-	fullTextResponse.Usage = usage
+	fullTextResponse.Usage = *usage
 	jsonResponse, err := json.Marshal(fullTextResponse)
 	if err != nil {
 		return openai.ErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError), nil
@@ -375,5 +470,5 @@ func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName st
 	c.Writer.Header().Set("Content-Type", "application/json")
 	c.Writer.WriteHeader(resp.StatusCode)
 	_, err = c.Writer.Write(jsonResponse)
-	return nil, &usage
+	return nil, usage
 }
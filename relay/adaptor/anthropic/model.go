@@ -1,5 +1,7 @@
 package anthropic
 
+import "github.com/LeXwDeX/one-api/relay/model"
+
 // https://docs.anthropic.com/claude/reference/messages_post
 
 type Metadata struct {
@@ -22,6 +24,9 @@ type Content struct {
 	Input     any    `json:"input,omitempty"`
 	Content   string `json:"content,omitempty"`
 	ToolUseId string `json:"tool_use_id,omitempty"`
+	// thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
 type Message struct {
@@ -39,6 +44,7 @@ type InputSchema struct {
 	Type       string `json:"type"`
 	Properties any    `json:"properties,omitempty"`
 	Required   any    `json:"required,omitempty"`
+	Defs       any    `json:"$defs,omitempty"`
 }
 
 type Request struct {
@@ -53,12 +59,47 @@ type Request struct {
 	TopK          int       `json:"top_k,omitempty"`
 	Tools         []Tool    `json:"tools,omitempty"`
 	ToolChoice    any       `json:"tool_choice,omitempty"`
+	Thinking      *Thinking `json:"thinking,omitempty"`
 	//Metadata    `json:"metadata,omitempty"`
 }
 
+// https://docs.anthropic.com/en/docs/build-with-claude/extended-thinking
+type Thinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// Add accumulates the usage reported across the events of a stream.
+func (u *Usage) Add(other *Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// ToUsage converts the usage into its OpenAI form, where the prompt tokens include
+// the tokens read from and written to the prompt cache.
+func (u *Usage) ToUsage() *model.Usage {
+	promptTokens := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	usage := &model.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      promptTokens + u.OutputTokens,
+	}
+	if u.CacheCreationInputTokens != 0 || u.CacheReadInputTokens != 0 {
+		usage.PromptTokensDetails = &model.PromptTokensDetails{
+			CachedTokens:     u.CacheReadInputTokens,
+			CacheWriteTokens: u.CacheCreationInputTokens,
+		}
+	}
+	return usage
 }
 
 type Error struct {
@@ -75,13 +116,14 @@ type Response struct {
 	StopReason   *string   `json:"stop_reason"`
 	StopSequence *string   `json:"stop_sequence"`
 	Usage        Usage     `json:"usage"`
-	Error        Error     `json:"error"`
+	Error        *Error    `json:"error,omitempty"`
 }
 
 type Delta struct {
 	Type         string  `json:"type"`
 	Text         string  `json:"text"`
 	PartialJson  string  `json:"partial_json,omitempty"`
+	Thinking     string  `json:"thinking,omitempty"`
 	StopReason   *string `json:"stop_reason"`
 	StopSequence *string `json:"stop_sequence"`
 }
@@ -94,3 +136,77 @@ type StreamResponse struct {
 	Delta        *Delta    `json:"delta"`
 	Usage        *Usage    `json:"usage"`
 }
+
+// IngressRequest is a Messages API request sent by a client to One API.
+// Unlike Request, it accepts every shorthand the Messages API allows,
+// e.g. string content and block-list system prompts.
+type IngressRequest struct {
+	Model         string             `json:"model"`
+	Messages      []IngressMessage   `json:"messages"`
+	System        any                `json:"system,omitempty"`
+	MaxTokens     int                `json:"max_tokens,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	TopK          int                `json:"top_k,omitempty"`
+	Tools         []IngressTool      `json:"tools,omitempty"`
+	ToolChoice    *IngressToolChoice `json:"tool_choice,omitempty"`
+	Metadata      *Metadata          `json:"metadata,omitempty"`
+}
+
+type IngressMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type IngressContent struct {
+	Type      string              `json:"type"`
+	Text      string              `json:"text,omitempty"`
+	Source    *IngressImageSource `json:"source,omitempty"`
+	Id        string              `json:"id,omitempty"`
+	Name      string              `json:"name,omitempty"`
+	Input     any                 `json:"input,omitempty"`
+	ToolUseId string              `json:"tool_use_id,omitempty"`
+	Content   any                 `json:"content,omitempty"`
+	IsError   bool                `json:"is_error,omitempty"`
+}
+
+type IngressImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Url       string `json:"url,omitempty"`
+}
+
+type IngressTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema,omitempty"`
+}
+
+type IngressToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// https://docs.anthropic.com/en/api/messages-count-tokens
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+type ErrorResponse struct {
+	Type  string `json:"type"`
+	Error Error  `json:"error"`
+}
+
+// StreamEvent is the payload of a Messages API server-sent event,
+// as produced by One API for ingress requests.
+type StreamEvent struct {
+	Type         string         `json:"type"`
+	Message      *Response      `json:"message,omitempty"`
+	Index        *int           `json:"index,omitempty"`
+	ContentBlock map[string]any `json:"content_block,omitempty"`
+	Delta        map[string]any `json:"delta,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"`
+}
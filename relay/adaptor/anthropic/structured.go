@@ -0,0 +1,111 @@
+package anthropic
+
+import (
+	"encoding/json"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// Claude has no response_format, so structured output is asked for as a call to this tool,
+// whose input is then handed back as the message content.
+const StructuredOutputToolName = "json_response"
+
+// getStructuredOutputTool returns the tool standing for the response format, if it asks for JSON.
+func getStructuredOutputTool(responseFormat *model.ResponseFormat) *Tool {
+	if responseFormat == nil {
+		return nil
+	}
+	tool := Tool{
+		Name:        StructuredOutputToolName,
+		Description: "Respond with a JSON object.",
+		InputSchema: InputSchema{Type: "object"},
+	}
+	switch responseFormat.Type {
+	case "json_object":
+	case "json_schema":
+		if responseFormat.JsonSchema == nil {
+			return nil
+		}
+		if responseFormat.JsonSchema.Description != "" {
+			tool.Description = responseFormat.JsonSchema.Description
+		}
+		if schema := responseFormat.JsonSchema.Schema; schema != nil {
+			tool.InputSchema = toInputSchema(schema)
+		}
+	default:
+		return nil
+	}
+	return &tool
+}
+
+func toInputSchema(schema map[string]any) InputSchema {
+	inputSchema := InputSchema{
+		Type:       "object",
+		Properties: schema["properties"],
+		Required:   schema["required"],
+		Defs:       schema["$defs"],
+	}
+	if schemaType, ok := schema["type"].(string); ok {
+		inputSchema.Type = schemaType
+	}
+	if inputSchema.Defs == nil {
+		inputSchema.Defs = schema["definitions"]
+	}
+	return inputSchema
+}
+
+// structuredOutputContent returns the JSON the structured output tool was called with.
+func structuredOutputContent(input any) string {
+	if input == nil {
+		return "{}"
+	}
+	content, _ := json.Marshal(input)
+	return string(content)
+}
+
+// StructuredOutputStream unwraps the structured output tool call across the events of a stream,
+// turning its input into content deltas.
+type StructuredOutputStream struct {
+	blockIndex int
+	used       bool
+	otherTools bool
+}
+
+// Unwrap rewrites the chunk converted from the event when it belongs to the structured output.
+func (s *StructuredOutputStream) Unwrap(claudeResponse *StreamResponse, response *openai.ChatCompletionsStreamResponse) {
+	switch claudeResponse.Type {
+	case "content_block_start":
+		if claudeResponse.ContentBlock == nil || claudeResponse.ContentBlock.Type != "tool_use" {
+			return
+		}
+		if claudeResponse.ContentBlock.Name != StructuredOutputToolName {
+			s.otherTools = true
+			return
+		}
+		s.blockIndex = claudeResponse.Index
+		s.used = true
+		for i := range response.Choices {
+			response.Choices[i].Delta.ToolCalls = nil
+			response.Choices[i].Delta.Content = ""
+		}
+	case "content_block_delta":
+		if !s.used || claudeResponse.Index != s.blockIndex || claudeResponse.Delta == nil || claudeResponse.Delta.Type != "input_json_delta" {
+			return
+		}
+		for i := range response.Choices {
+			response.Choices[i].Delta.ToolCalls = nil
+			response.Choices[i].Delta.Content = claudeResponse.Delta.PartialJson
+		}
+	case "message_delta":
+		if !s.used || s.otherTools {
+			return
+		}
+		for i := range response.Choices {
+			if reason := response.Choices[i].FinishReason; reason != nil && *reason == "tool_calls" {
+				stop := "stop"
+				response.Choices[i].FinishReason = &stop
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertRequestStructuredOutput(t *testing.T) {
+	textRequest := relaymodel.GeneralOpenAIRequest{
+		Model:    "claude-3-5-sonnet-latest",
+		Messages: []relaymodel.Message{{Role: "user", Content: "name a city"}},
+		ResponseFormat: &relaymodel.ResponseFormat{
+			Type: "json_schema",
+			JsonSchema: &relaymodel.JSONSchema{
+				Name: "city",
+				Schema: map[string]any{
+					"type":                 "object",
+					"properties":           map[string]any{"name": map[string]any{"type": "string"}},
+					"required":             []any{"name"},
+					"additionalProperties": false,
+				},
+			},
+		},
+	}
+	claudeRequest := ConvertRequest(textRequest)
+	require.Len(t, claudeRequest.Tools, 1)
+	assert.Equal(t, StructuredOutputToolName, claudeRequest.Tools[0].Name)
+	assert.Equal(t, []any{"name"}, claudeRequest.Tools[0].InputSchema.Required)
+	assert.Equal(t, map[string]string{"type": "tool", "name": StructuredOutputToolName}, claudeRequest.ToolChoice)
+
+	// with tools of its own the model keeps the choice
+	textRequest.Tools = []relaymodel.Tool{{Type: "function", Function: relaymodel.Function{Name: "search", Parameters: map[string]any{"type": "object"}}}}
+	claudeRequest = ConvertRequest(textRequest)
+	require.Len(t, claudeRequest.Tools, 2)
+	toolChoice, _ := json.Marshal(claudeRequest.ToolChoice)
+	assert.JSONEq(t, `{"type":"auto"}`, string(toolChoice))
+}
+
+func TestResponseClaude2OpenAIStructuredOutput(t *testing.T) {
+	stopReason := "tool_use"
+	response := ResponseClaude2OpenAI(&Response{
+		Id: "msg_1",
+		Content: []Content{
+			{Type: "tool_use", Id: "toolu_1", Name: StructuredOutputToolName, Input: map[string]any{"name": "Paris"}},
+		},
+		StopReason: &stopReason,
+	})
+	choice := response.Choices[0]
+	assert.Equal(t, `{"name":"Paris"}`, choice.Message.Content)
+	assert.Empty(t, choice.Message.ToolCalls)
+	assert.Equal(t, "stop", choice.FinishReason)
+}
+
+func TestStructuredOutputStream(t *testing.T) {
+	events := []string{
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"json_response","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"name\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+	}
+	var structuredOutput StructuredOutputStream
+	content := ""
+	var finishReason string
+	for _, event := range events {
+		var claudeResponse StreamResponse
+		require.NoError(t, json.Unmarshal([]byte(event), &claudeResponse))
+		response, _ := StreamResponseClaude2OpenAI(&claudeResponse)
+		require.NotNil(t, response)
+		structuredOutput.Unwrap(&claudeResponse, response)
+		choice := response.Choices[0]
+		assert.Empty(t, choice.Delta.ToolCalls)
+		if text, ok := choice.Delta.Content.(string); ok {
+			content += text
+		}
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
+		}
+	}
+	assert.Equal(t, `{"name":"Paris"}`, content)
+	assert.Equal(t, "stop", finishReason)
+}
@@ -0,0 +1,89 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertRequestThinking(t *testing.T) {
+	effort := "low"
+	temperature := 0.2
+	textRequest := relaymodel.GeneralOpenAIRequest{
+		Model:           "claude-3-7-sonnet-latest",
+		Messages:        []relaymodel.Message{{Role: "user", Content: "why is the sky blue"}},
+		MaxTokens:       1000,
+		Temperature:     &temperature,
+		ReasoningEffort: &effort,
+		ResponseFormat:  &relaymodel.ResponseFormat{Type: "json_object"},
+	}
+	claudeRequest := ConvertRequest(textRequest)
+	require.NotNil(t, claudeRequest.Thinking)
+	assert.Equal(t, "enabled", claudeRequest.Thinking.Type)
+	assert.Equal(t, 4096, claudeRequest.Thinking.BudgetTokens)
+	assert.Equal(t, 5096, claudeRequest.MaxTokens)
+	assert.Nil(t, claudeRequest.Temperature)
+	toolChoice, _ := json.Marshal(claudeRequest.ToolChoice)
+	assert.JSONEq(t, `{"type":"auto"}`, string(toolChoice))
+
+	effort = "none"
+	claudeRequest = ConvertRequest(textRequest)
+	assert.Nil(t, claudeRequest.Thinking)
+	assert.Equal(t, 1000, claudeRequest.MaxTokens)
+}
+
+func TestConvertRequestThinkingUnsupported(t *testing.T) {
+	effort := "high"
+	textRequest := relaymodel.GeneralOpenAIRequest{
+		Model:           "claude-3-5-sonnet-20241022",
+		Messages:        []relaymodel.Message{{Role: "user", Content: "why is the sky blue"}},
+		ReasoningEffort: &effort,
+	}
+	assert.Nil(t, ConvertRequest(textRequest).Thinking)
+
+	textRequest.Model = "anthropic.claude-sonnet-4-20250514-v1:0"
+	assert.NotNil(t, ConvertRequest(textRequest).Thinking)
+
+	// earlier tool calls lack the signed thinking blocks they were made with
+	textRequest.Messages = append(textRequest.Messages,
+		relaymodel.Message{Role: "assistant", ToolCalls: []relaymodel.Tool{{Id: "call_1", Type: "function", Function: relaymodel.Function{Name: "weather"}}}},
+		relaymodel.Message{Role: "tool", ToolCallId: "call_1", Content: "sunny"},
+	)
+	assert.Nil(t, ConvertRequest(textRequest).Thinking)
+}
+
+func TestResponseClaude2OpenAIThinking(t *testing.T) {
+	stopReason := "end_turn"
+	response := ResponseClaude2OpenAI(&Response{
+		Id: "msg_1",
+		Content: []Content{
+			{Type: "thinking", Thinking: "Rayleigh scattering.", Signature: "sig"},
+			{Type: "text", Text: "Because of "},
+			{Type: "text", Text: "Rayleigh scattering."},
+		},
+		StopReason: &stopReason,
+	})
+	choice := response.Choices[0]
+	assert.Equal(t, "Because of Rayleigh scattering.", choice.Message.Content)
+	assert.Equal(t, "Rayleigh scattering.", choice.Message.ReasoningContent)
+}
+
+func TestStreamResponseClaude2OpenAIThinking(t *testing.T) {
+	var claudeResponse StreamResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me think."}}`), &claudeResponse))
+	response, _ := StreamResponseClaude2OpenAI(&claudeResponse)
+	require.NotNil(t, response)
+	assert.Equal(t, "Let me think.", response.Choices[0].Delta.ReasoningContent)
+	assert.Equal(t, "", response.Choices[0].Delta.Content)
+}
+
+func TestSetReasoningTokens(t *testing.T) {
+	usage := &relaymodel.Usage{CompletionTokens: 2}
+	setReasoningTokens(usage, "a rather long chain of thought", "claude-3-7-sonnet-latest")
+	require.NotNil(t, usage.CompletionTokensDetails)
+	assert.Equal(t, 2, usage.CompletionTokensDetails.ReasoningTokens)
+}
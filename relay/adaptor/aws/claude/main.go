@@ -88,15 +88,11 @@ func Handler(c *gin.Context, awsCli *bedrockruntime.Client, modelName string) (*
 
 	openaiResp := anthropic.ResponseClaude2OpenAI(claudeResponse)
 	openaiResp.Model = modelName
-	usage := relaymodel.Usage{
-		PromptTokens:     claudeResponse.Usage.InputTokens,
-		CompletionTokens: claudeResponse.Usage.OutputTokens,
-		TotalTokens:      claudeResponse.Usage.InputTokens + claudeResponse.Usage.OutputTokens,
-	}
-	openaiResp.Usage = usage
+	usage := claudeResponse.Usage.ToUsage()
+	openaiResp.Usage = *usage
 
 	c.JSON(http.StatusOK, openaiResp)
-	return nil, &usage
+	return nil, usage
 }
 
 func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.ErrorWithStatusCode, *relaymodel.Usage) {
@@ -137,9 +133,10 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 	defer stream.Close()
 
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
-	var usage relaymodel.Usage
+	var claudeUsage anthropic.Usage
 	var id string
 	var lastToolCallChoice openai.ChatCompletionsStreamResponseChoice
+	var structuredOutput anthropic.StructuredOutputStream
 
 	c.Stream(func(w io.Writer) bool {
 		event, ok := <-stream.Events()
@@ -158,9 +155,11 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 			}
 
 			response, meta := anthropic.StreamResponseClaude2OpenAI(claudeResp)
+			if response != nil {
+				structuredOutput.Unwrap(claudeResp, response)
+			}
 			if meta != nil {
-				usage.PromptTokens += meta.Usage.InputTokens
-				usage.CompletionTokens += meta.Usage.OutputTokens
+				claudeUsage.Add(&meta.Usage)
 				if len(meta.Id) > 0 { // only message_start has an id, otherwise it's a finish_reason event.
 					id = fmt.Sprintf("chatcmpl-%s", meta.Id)
 					return true
@@ -203,5 +202,5 @@ func StreamHandler(c *gin.Context, awsCli *bedrockruntime.Client) (*relaymodel.E
 		}
 	})
 
-	return nil, &usage
+	return nil, claudeUsage.ToUsage()
 }
@@ -10,9 +10,13 @@ import (
 	"github.com/LeXwDeX/one-api/relay/adaptor"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
 )
 
-type Adaptor struct{}
+type Adaptor struct {
+	// documents of the rerank request, kept when they are to be returned
+	rerankDocuments []any
+}
 
 // ConvertImageRequest implements adaptor.Adaptor.
 func (*Adaptor) ConvertImageRequest(request *model.ImageRequest) (any, error) {
@@ -26,6 +30,9 @@ func (a *Adaptor) Init(meta *meta.Meta) {
 }
 
 func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
+	if meta.Mode == relaymode.Rerank {
+		return fmt.Sprintf("%s/v2/rerank", meta.BaseURL), nil
+	}
 	return fmt.Sprintf("%s/v1/chat", meta.BaseURL), nil
 }
 
@@ -42,6 +49,22 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 	return ConvertRequest(*request), nil
 }
 
+// ConvertRerankRequest implements adaptor.Reranker.
+func (a *Adaptor) ConvertRerankRequest(request *model.RerankRequest) (any, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	if request.ReturnDocuments != nil && *request.ReturnDocuments {
+		a.rerankDocuments = request.Documents
+	}
+	return ConvertRerankRequest(request), nil
+}
+
+// DoRerankResponse implements adaptor.Reranker.
+func (a *Adaptor) DoRerankResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (*model.RerankResponse, *model.ErrorWithStatusCode) {
+	return RerankHandler(c, resp, a.rerankDocuments)
+}
+
 func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
 	return adaptor.DoRequestHelper(a, c, meta, requestBody)
 }
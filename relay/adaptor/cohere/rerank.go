@@ -0,0 +1,96 @@
+package cohere
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// RerankRequest is the request of the v2 rerank API, which takes documents as strings only.
+// https://docs.cohere.com/reference/rerank
+type RerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	MaxTokensPerDoc int      `json:"max_tokens_per_doc,omitempty"`
+}
+
+type RerankResponse struct {
+	Id      string               `json:"id"`
+	Results []model.RerankResult `json:"results"`
+	Meta    *model.RerankMeta    `json:"meta,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+func documentToString(document any) string {
+	switch v := document.(type) {
+	case string:
+		return v
+	case map[string]any:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+	}
+	// structured documents are ranked by their JSON
+	jsonDocument, _ := json.Marshal(document)
+	return string(jsonDocument)
+}
+
+func ConvertRerankRequest(request *model.RerankRequest) *RerankRequest {
+	rerankRequest := RerankRequest{
+		Model:     request.Model,
+		Query:     request.Query,
+		Documents: make([]string, 0, len(request.Documents)),
+		TopN:      request.TopN,
+	}
+	for _, document := range request.Documents {
+		rerankRequest.Documents = append(rerankRequest.Documents, documentToString(document))
+	}
+	return &rerankRequest
+}
+
+// RerankHandler returns the results in the Jina-compatible shape. v2 no longer returns the
+// documents, so they are filled in from the request when asked for.
+func RerankHandler(c *gin.Context, resp *http.Response, documents []any) (*model.RerankResponse, *model.ErrorWithStatusCode) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError)
+	}
+	var cohereResponse RerankResponse
+	err = json.Unmarshal(responseBody, &cohereResponse)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if cohereResponse.Id == "" {
+		return nil, openai.ErrorWrapper(fmt.Errorf("rerank failed: %s", cohereResponse.Message), "cohere_rerank_failed", resp.StatusCode)
+	}
+	rerankResponse := model.RerankResponse{
+		Id:      cohereResponse.Id,
+		Results: cohereResponse.Results,
+		Meta:    cohereResponse.Meta,
+	}
+	for i, result := range rerankResponse.Results {
+		if documents != nil && result.Index >= 0 && result.Index < len(documents) {
+			rerankResponse.Results[i].Document = map[string]string{"text": documentToString(documents[result.Index])}
+		}
+	}
+	jsonResponse, err := json.Marshal(rerankResponse)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "marshal_response_body_failed", http.StatusInternalServerError)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = c.Writer.Write(jsonResponse)
+	return &rerankResponse, nil
+}
@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/LeXwDeX/one-api/common/client"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/helper"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func SetupCommonRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) {
@@ -18,12 +23,56 @@ func SetupCommonRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta
 	}
 }
 
+// unforwardableHeaders carry the credentials of the client or describe the client connection,
+// they are never passed through whatever the channel says. Every header TokenAuth reads the
+// key from belongs here.
+var unforwardableHeaders = map[string]bool{
+	"Authorization":          true,
+	"X-Api-Key":              true,
+	"X-Goog-Api-Key":         true,
+	"Api-Key":                true,
+	"Sec-Websocket-Protocol": true,
+	"Cookie":                 true,
+	"Host":                   true,
+	"Content-Length":         true,
+	"Transfer-Encoding":      true,
+	"Connection":             true,
+}
+
+// SetupChannelRequestHeader applies the headers configured on the channel, after the adaptor
+// set its own: the client headers to forward first, then the channel's own headers.
+func SetupChannelRequestHeader(c *gin.Context, header http.Header, meta *meta.Meta) {
+	for _, name := range meta.Config.ForwardHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if unforwardableHeaders[name] {
+			continue
+		}
+		if values := c.Request.Header.Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+	if len(meta.Config.Headers) == 0 {
+		return
+	}
+	replacer := strings.NewReplacer(
+		"{user_id}", strconv.Itoa(meta.UserId),
+		"{token_id}", strconv.Itoa(meta.TokenId),
+		"{token_name}", meta.TokenName,
+		"{request_id}", c.GetString(helper.RequestIdKey),
+		"{model}", meta.ActualModelName,
+	)
+	for name, value := range meta.Config.Headers {
+		header.Set(name, replacer.Replace(value))
+	}
+}
+
 func DoRequestHelper(a Adaptor, c *gin.Context, meta *meta.Meta, requestBody io.Reader) (*http.Response, error) {
 	fullRequestURL, err := a.GetRequestURL(meta)
 	if err != nil {
 		return nil, fmt.Errorf("get request url failed: %w", err)
 	}
-	req, err := http.NewRequest(c.Request.Method, fullRequestURL, requestBody)
+	// bound to the client request, so that the upstream call is cancelled once the client is gone
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, fullRequestURL, requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("new request failed: %w", err)
 	}
@@ -31,7 +80,8 @@ func DoRequestHelper(a Adaptor, c *gin.Context, meta *meta.Meta, requestBody io.
 	if err != nil {
 		return nil, fmt.Errorf("setup request header failed: %w", err)
 	}
-	resp, err := DoRequest(c, req)
+	SetupChannelRequestHeader(c, req.Header, meta)
+	resp, err := DoRequestWithTimeouts(c, req, meta)
 	if err != nil {
 		return nil, fmt.Errorf("do request failed: %w", err)
 	}
@@ -39,10 +89,13 @@ func DoRequestHelper(a Adaptor, c *gin.Context, meta *meta.Meta, requestBody io.
 }
 
 func DoRequest(c *gin.Context, req *http.Request) (*http.Response, error) {
+	startTime := time.Now()
 	resp, err := client.HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	// the response headers mark the first byte, for latency-aware routing
+	c.Set(ctxkey.UpstreamFirstByteLatency, time.Since(startTime))
 	if resp == nil {
 		return nil, errors.New("resp is nil")
 	}
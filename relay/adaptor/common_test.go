@@ -0,0 +1,58 @@
+package adaptor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/meta"
+)
+
+func TestSetupChannelRequestHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	c.Request.Header.Set("Authorization", "Bearer sk-client")
+	c.Request.Header.Set("x-api-key", "sk-client")
+	c.Request.Header.Set("x-goog-api-key", "sk-client")
+	c.Request.Header.Set("api-key", "sk-client")
+	c.Request.Header.Set("Sec-WebSocket-Protocol", "realtime, openai-insecure-api-key.sk-client")
+	c.Request.Header.Add("anthropic-beta", "a")
+	c.Request.Header.Add("anthropic-beta", "b")
+	c.Set(helper.RequestIdKey, "20260101000000123")
+	m := &meta.Meta{
+		UserId:          7,
+		TokenName:       "ci",
+		ActualModelName: "claude-sonnet-4",
+		Config: model.ChannelConfig{
+			ForwardHeaders: []string{"anthropic-beta", "authorization", "x-api-key", "X-Goog-Api-Key",
+				"api-key", "Sec-WebSocket-Protocol", "OpenAI-Organization"},
+			Headers: map[string]string{
+				"X-Gateway-User": "{user_id}/{token_name}",
+				"X-Request-Id":   "{request_id}",
+				"HTTP-Referer":   "https://example.com",
+			},
+		},
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer sk-upstream")
+	header.Set("HTTP-Referer", "https://github.com/LeXwDeX/one-api")
+	SetupChannelRequestHeader(c, header, m)
+
+	assert.Equal(t, []string{"a", "b"}, header.Values("Anthropic-Beta"))
+	assert.Equal(t, "Bearer sk-upstream", header.Get("Authorization"))
+	assert.Empty(t, header.Get("X-Api-Key"))
+	assert.Empty(t, header.Get("X-Goog-Api-Key"))
+	assert.Empty(t, header.Get("Api-Key"))
+	assert.Empty(t, header.Get("Sec-WebSocket-Protocol"))
+	assert.Empty(t, header.Get("OpenAI-Organization"))
+	assert.Equal(t, "7/ci", header.Get("X-Gateway-User"))
+	assert.Equal(t, "20260101000000123", header.Get("X-Request-Id"))
+	assert.Equal(t, "https://example.com", header.Get("HTTP-Referer"))
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/helper"
 	channelhelper "github.com/LeXwDeX/one-api/relay/adaptor"
-	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	"github.com/LeXwDeX/one-api/relay/model"
 	"github.com/LeXwDeX/one-api/relay/relaymode"
@@ -79,9 +78,7 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
 	if meta.IsStream {
-		var responseText string
-		err, responseText = StreamHandler(c, resp)
-		usage = openai.ResponseText2Usage(responseText, meta.ActualModelName, meta.PromptTokens)
+		err, usage = StreamHandler(c, resp, meta.PromptTokens, meta.ActualModelName)
 	} else {
 		switch meta.Mode {
 		case relaymode.Embeddings:
@@ -0,0 +1,388 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/LeXwDeX/one-api/common/conv"
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// The functions below serve the Gemini-native ingress: clients talk generateContent to One API,
+// while the selected channel may be of any type.
+
+const modelRole = "model"
+
+func ingressContentText(content *IngressContent) string {
+	if content == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range content.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func finishReasonOpenAI2Gemini(reason string) string {
+	switch reason {
+	case "length":
+		return "MAX_TOKENS"
+	case "content_filter":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+func usageOpenAI2Gemini(usage *model.Usage) *UsageMetadata {
+	if usage == nil {
+		return nil
+	}
+	return &UsageMetadata{
+		PromptTokenCount:        usage.PromptTokens,
+		CandidatesTokenCount:    usage.CompletionTokens,
+		TotalTokenCount:         usage.TotalTokens,
+		CachedContentTokenCount: usage.GetCachedTokens(),
+	}
+}
+
+// ConvertIngressRequest translates a generateContent request into a chat completions request.
+func ConvertIngressRequest(request *IngressRequest, modelName string, stream bool) (*model.GeneralOpenAIRequest, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	textRequest := &model.GeneralOpenAIRequest{
+		Model:  modelName,
+		Stream: stream,
+	}
+	if config := request.GenerationConfig; config != nil {
+		textRequest.Temperature = config.Temperature
+		textRequest.TopP = config.TopP
+		textRequest.TopK = int(config.TopK)
+		textRequest.MaxTokens = config.MaxOutputTokens
+		textRequest.N = config.CandidateCount
+		if len(config.StopSequences) > 0 {
+			textRequest.Stop = config.StopSequences
+		}
+		if config.ResponseMimeType == mimeTypeMap["json_object"] {
+			textRequest.ResponseFormat = &model.ResponseFormat{Type: "json_object"}
+			if schema, ok := config.ResponseSchema.(map[string]any); ok {
+				textRequest.ResponseFormat = &model.ResponseFormat{
+					Type: "json_schema",
+					JsonSchema: &model.JSONSchema{
+						Name:   "response",
+						Schema: schema,
+					},
+				}
+			}
+		}
+	}
+	for _, tool := range request.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			textRequest.Tools = append(textRequest.Tools, model.Tool{
+				Type: "function",
+				Function: model.Function{
+					Name:        declaration.Name,
+					Description: declaration.Description,
+					Parameters:  declaration.Parameters,
+				},
+			})
+		}
+	}
+	if request.ToolConfig != nil && request.ToolConfig.FunctionCallingConfig != nil {
+		callingConfig := request.ToolConfig.FunctionCallingConfig
+		switch callingConfig.Mode {
+		case "AUTO":
+			textRequest.ToolChoice = "auto"
+		case "NONE":
+			textRequest.ToolChoice = "none"
+		case "ANY":
+			textRequest.ToolChoice = "required"
+			if len(callingConfig.AllowedFunctionNames) == 1 {
+				textRequest.ToolChoice = map[string]any{
+					"type": "function",
+					"function": map[string]any{
+						"name": callingConfig.AllowedFunctionNames[0],
+					},
+				}
+			}
+		}
+	}
+	if system := ingressContentText(request.SystemInstruction); system != "" {
+		textRequest.Messages = append(textRequest.Messages, model.Message{
+			Role:    role.System,
+			Content: system,
+		})
+	}
+	// Gemini matches function responses to calls by name, chat completions by id
+	var callCount int
+	pendingCalls := make(map[string][]string)
+	for _, content := range request.Contents {
+		if content.Role == modelRole {
+			message := model.Message{Role: role.Assistant}
+			var text string
+			for _, part := range content.Parts {
+				text += part.Text
+				if part.FunctionCall == nil {
+					continue
+				}
+				callCount++
+				id := fmt.Sprintf("call_%d", callCount)
+				pendingCalls[part.FunctionCall.FunctionName] = append(pendingCalls[part.FunctionCall.FunctionName], id)
+				arguments, _ := json.Marshal(part.FunctionCall.Arguments)
+				message.ToolCalls = append(message.ToolCalls, model.Tool{
+					Id:   id,
+					Type: "function",
+					Function: model.Function{
+						Name:      part.FunctionCall.FunctionName,
+						Arguments: string(arguments),
+					},
+				})
+			}
+			message.Content = text
+			textRequest.Messages = append(textRequest.Messages, message)
+			continue
+		}
+		var parts []any
+		for _, part := range content.Parts {
+			switch {
+			case part.FunctionResponse != nil:
+				var id string
+				if ids := pendingCalls[part.FunctionResponse.Name]; len(ids) > 0 {
+					id, pendingCalls[part.FunctionResponse.Name] = ids[0], ids[1:]
+				}
+				response, _ := json.Marshal(part.FunctionResponse.Response)
+				textRequest.Messages = append(textRequest.Messages, model.Message{
+					Role:       role.Tool,
+					Content:    string(response),
+					ToolCallId: id,
+				})
+			case part.InlineData != nil:
+				parts = append(parts, map[string]any{
+					"type": model.ContentTypeImageURL,
+					"image_url": map[string]any{
+						"url": fmt.Sprintf("data:%s;base64,%s", part.InlineData.MimeType, part.InlineData.Data),
+					},
+				})
+			case part.FileData != nil:
+				parts = append(parts, map[string]any{
+					"type": model.ContentTypeImageURL,
+					"image_url": map[string]any{
+						"url": part.FileData.FileUri,
+					},
+				})
+			case part.Text != "":
+				parts = append(parts, map[string]any{
+					"type": model.ContentTypeText,
+					"text": part.Text,
+				})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		textRequest.Messages = append(textRequest.Messages, model.Message{
+			Role:    role.User,
+			Content: parts,
+		})
+	}
+	return textRequest, nil
+}
+
+// ConvertIngressEmbeddingRequest translates an embedContent request into an embeddings request.
+func ConvertIngressEmbeddingRequest(request *IngressEmbeddingRequest, modelName string) *model.GeneralOpenAIRequest {
+	return &model.GeneralOpenAIRequest{
+		Model:      modelName,
+		Input:      ingressContentText(&request.Content),
+		Dimensions: request.OutputDimensionality,
+	}
+}
+
+func parseFunctionArguments(arguments any) any {
+	args := make(map[string]any)
+	if argumentsText := conv.AsString(arguments); argumentsText != "" {
+		_ = json.Unmarshal([]byte(argumentsText), &args)
+	}
+	return args
+}
+
+// ResponseOpenAI2Gemini converts a chat completions response into a generateContent response.
+func ResponseOpenAI2Gemini(textResponse *openai.TextResponse) *ChatResponse {
+	response := ChatResponse{
+		Candidates:    make([]ChatCandidate, 0, len(textResponse.Choices)),
+		UsageMetadata: usageOpenAI2Gemini(&textResponse.Usage),
+		ModelVersion:  textResponse.Model,
+	}
+	for _, choice := range textResponse.Choices {
+		candidate := ChatCandidate{
+			Content: ChatContent{
+				Role:  modelRole,
+				Parts: make([]Part, 0),
+			},
+			FinishReason: finishReasonOpenAI2Gemini(choice.FinishReason),
+			Index:        int64(choice.Index),
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			candidate.Content.Parts = append(candidate.Content.Parts, Part{Text: text})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			candidate.Content.Parts = append(candidate.Content.Parts, Part{
+				FunctionCall: &FunctionCall{
+					FunctionName: toolCall.Function.Name,
+					Arguments:    parseFunctionArguments(toolCall.Function.Arguments),
+				},
+			})
+		}
+		response.Candidates = append(response.Candidates, candidate)
+	}
+	return &response
+}
+
+// ErrorResponseOpenAI2Gemini wraps a relay error the way the Gemini API reports errors.
+func ErrorResponseOpenAI2Gemini(err *model.ErrorWithStatusCode) *ErrorResponse {
+	status := "INTERNAL"
+	switch err.StatusCode {
+	case http.StatusBadRequest:
+		status = "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		status = "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		status = "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		status = "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		status = "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		status = "UNAVAILABLE"
+	}
+	return &ErrorResponse{
+		Error: Error{
+			Code:    err.StatusCode,
+			Message: err.Message,
+			Status:  status,
+		},
+	}
+}
+
+type ingressToolCall struct {
+	name      string
+	arguments string
+}
+
+// IngressStreamEncoder re-encodes a chat completions stream as streamGenerateContent chunks.
+// Gemini sends function calls whole, so tool call deltas are collected until the stream ends.
+type IngressStreamEncoder struct {
+	modelName    string
+	toolCalls    []*ingressToolCall
+	toolIndexes  map[int]int
+	finishReason string
+	usage        *model.Usage
+	// jsonArray is set when streamGenerateContent is called without alt=sse; that form
+	// is served from a non-stream upstream request as a single element array
+	jsonArray bool
+}
+
+func NewIngressStreamEncoder(modelName string, jsonArray bool) *IngressStreamEncoder {
+	return &IngressStreamEncoder{
+		modelName:   modelName,
+		toolIndexes: make(map[int]int),
+		jsonArray:   jsonArray,
+	}
+}
+
+func (e *IngressStreamEncoder) chunk(parts []Part, finishReason string, usage *model.Usage) render.Event {
+	candidate := ChatCandidate{
+		Content: ChatContent{
+			Role:  modelRole,
+			Parts: parts,
+		},
+		FinishReason: finishReason,
+	}
+	return render.Event{Data: ChatResponse{
+		Candidates:    []ChatCandidate{candidate},
+		UsageMetadata: usageOpenAI2Gemini(usage),
+		ModelVersion:  e.modelName,
+	}}
+}
+
+func (e *IngressStreamEncoder) EncodeStreamChunk(chunk *openai.ChatCompletionsStreamResponse) []render.Event {
+	var events []render.Event
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			e.finishReason = *choice.FinishReason
+		}
+		if text := conv.AsString(choice.Delta.Content); text != "" {
+			events = append(events, e.chunk([]Part{{Text: text}}, "", nil))
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			var call *ingressToolCall
+			if toolCall.Index != nil {
+				if i, ok := e.toolIndexes[*toolCall.Index]; ok {
+					call = e.toolCalls[i]
+				}
+			} else if toolCall.Id == "" && len(e.toolCalls) > 0 {
+				call = e.toolCalls[len(e.toolCalls)-1]
+			}
+			if call == nil {
+				call = &ingressToolCall{}
+				e.toolCalls = append(e.toolCalls, call)
+				if toolCall.Index != nil {
+					e.toolIndexes[*toolCall.Index] = len(e.toolCalls) - 1
+				}
+			}
+			if toolCall.Function.Name != "" {
+				call.name = toolCall.Function.Name
+			}
+			call.arguments += conv.AsString(toolCall.Function.Arguments)
+		}
+	}
+	return events
+}
+
+func (e *IngressStreamEncoder) EncodeStreamEnd(usage *model.Usage) []render.Event {
+	if usage == nil {
+		usage = e.usage
+	}
+	parts := make([]Part, 0, len(e.toolCalls))
+	for _, call := range e.toolCalls {
+		parts = append(parts, Part{
+			FunctionCall: &FunctionCall{
+				FunctionName: call.name,
+				Arguments:    parseFunctionArguments(call.arguments),
+			},
+		})
+	}
+	return []render.Event{e.chunk(parts, finishReasonOpenAI2Gemini(e.finishReason), usage)}
+}
+
+func (e *IngressStreamEncoder) EncodeResponse(textResponse *openai.TextResponse) any {
+	response := ResponseOpenAI2Gemini(textResponse)
+	if response.ModelVersion == "" {
+		response.ModelVersion = e.modelName
+	}
+	if e.jsonArray {
+		return []*ChatResponse{response}
+	}
+	return response
+}
+
+// EncodeEmbeddingResponse converts an embeddings response into an embedContent response.
+func EncodeEmbeddingResponse(embeddingResponse *openai.EmbeddingResponse) any {
+	response := IngressEmbeddingResponse{}
+	if len(embeddingResponse.Data) > 0 {
+		response.Embedding.Values = embeddingResponse.Data[0].Embedding
+	}
+	return response
+}
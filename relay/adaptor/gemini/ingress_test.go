@@ -0,0 +1,69 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertIngressRequest(t *testing.T) {
+	body := `{
+		"systemInstruction": {"parts": [{"text": "be brief"}]},
+		"contents": [
+			{"role": "user", "parts": [{"text": "weather?"}, {"inlineData": {"mimeType": "image/png", "data": "aGk="}}]},
+			{"role": "model", "parts": [{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}]},
+			{"role": "user", "parts": [{"functionResponse": {"name": "get_weather", "response": {"sky": "sunny"}}}]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "get_weather", "parameters": {"type": "object"}}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY"}},
+		"generationConfig": {"maxOutputTokens": 64, "stopSequences": ["END"], "responseMimeType": "application/json"}
+	}`
+	var request IngressRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	textRequest, err := ConvertIngressRequest(&request, "gemini-1.5-pro", true)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-1.5-pro", textRequest.Model)
+	assert.True(t, textRequest.Stream)
+	assert.Equal(t, 64, textRequest.MaxTokens)
+	assert.Equal(t, "required", textRequest.ToolChoice)
+	assert.Equal(t, "json_object", textRequest.ResponseFormat.Type)
+	require.Len(t, textRequest.Tools, 1)
+
+	require.Len(t, textRequest.Messages, 4)
+	assert.Equal(t, role.System, textRequest.Messages[0].Role)
+	require.Len(t, textRequest.Messages[1].ParseContent(), 2)
+	assert.Equal(t, `{"city":"Paris"}`, textRequest.Messages[2].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, role.Tool, textRequest.Messages[3].Role)
+	assert.Equal(t, textRequest.Messages[2].ToolCalls[0].Id, textRequest.Messages[3].ToolCallId)
+	assert.Equal(t, `{"sky":"sunny"}`, textRequest.Messages[3].StringContent())
+}
+
+func TestIngressStreamEncoder(t *testing.T) {
+	encoder := NewIngressStreamEncoder("gemini-1.5-pro", false)
+	stop := "stop"
+	index := 0
+	var events int
+	for _, chunk := range []openai.ChatCompletionsStreamResponse{
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{Content: "Hi"}}}},
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Index: &index, Id: "call_1", Function: relaymodel.Function{Name: "f", Arguments: `{"a":`}}}}}}},
+		{Choices: []openai.ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Index: &index, Function: relaymodel.Function{Arguments: "1}"}}}}, FinishReason: &stop}}},
+	} {
+		events += len(encoder.EncodeStreamChunk(&chunk))
+	}
+	assert.Equal(t, 1, events)
+
+	end := encoder.EncodeStreamEnd(&relaymodel.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	require.Len(t, end, 1)
+	last := end[0].Data.(ChatResponse)
+	assert.Equal(t, "STOP", last.Candidates[0].FinishReason)
+	assert.Equal(t, "f", last.Candidates[0].Content.Parts[0].FunctionCall.FunctionName)
+	assert.Equal(t, map[string]any{"a": float64(1)}, last.Candidates[0].Content.Parts[0].FunctionCall.Arguments)
+	assert.Equal(t, 5, last.UsageMetadata.TotalTokenCount)
+}
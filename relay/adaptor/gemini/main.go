@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/LeXwDeX/one-api/common/render"
@@ -35,6 +37,23 @@ var mimeTypeMap = map[string]string{
 }
 
 // Setting safety to the lowest possible values since Gemini is already powerless enough
+var geminiVersionRegexp = regexp.MustCompile(`gemini-(\d+)(?:\.(\d+))?`)
+
+// isThinkingModel tells whether the model takes a thinking config: the thinking experiments and
+// Gemini 2.5 on, the others reject the request.
+func isThinkingModel(modelName string) bool {
+	if strings.Contains(modelName, "thinking") {
+		return true
+	}
+	version := geminiVersionRegexp.FindStringSubmatch(modelName)
+	if version == nil {
+		return false
+	}
+	major, _ := strconv.Atoi(version[1])
+	minor, _ := strconv.Atoi(version[2])
+	return major > 2 || (major == 2 && minor >= 5)
+}
+
 func ConvertRequest(textRequest model.GeneralOpenAIRequest) *ChatRequest {
 	geminiRequest := ChatRequest{
 		Contents: make([]ChatContent, 0, len(textRequest.Messages)),
@@ -71,10 +90,18 @@ func ConvertRequest(textRequest model.GeneralOpenAIRequest) *ChatRequest {
 			geminiRequest.GenerationConfig.ResponseMimeType = mimeType
 		}
 		if textRequest.ResponseFormat.JsonSchema != nil {
-			geminiRequest.GenerationConfig.ResponseSchema = textRequest.ResponseFormat.JsonSchema.Schema
+			if schema := textRequest.ResponseFormat.JsonSchema.Schema; schema != nil {
+				geminiRequest.GenerationConfig.ResponseSchema = convertSchema(schema)
+			}
 			geminiRequest.GenerationConfig.ResponseMimeType = mimeTypeMap["json_object"]
 		}
 	}
+	if budget, ok := textRequest.GetThinkingBudget(); ok && isThinkingModel(textRequest.Model) {
+		geminiRequest.GenerationConfig.ThinkingConfig = &ThinkingConfig{
+			ThinkingBudget:  &budget,
+			IncludeThoughts: budget > 0,
+		}
+	}
 	if textRequest.Tools != nil {
 		functions := make([]model.Function, 0, len(textRequest.Tools))
 		for _, tool := range textRequest.Tools {
@@ -187,16 +214,29 @@ func ConvertEmbeddingRequest(request model.GeneralOpenAIRequest) *BatchEmbedding
 type ChatResponse struct {
 	Candidates     []ChatCandidate    `json:"candidates"`
 	PromptFeedback ChatPromptFeedback `json:"promptFeedback"`
+	UsageMetadata  *UsageMetadata     `json:"usageMetadata,omitempty"`
+	ModelVersion   string             `json:"modelVersion,omitempty"`
 }
 
 func (g *ChatResponse) GetResponseText() string {
-	if g == nil {
+	if g == nil || len(g.Candidates) == 0 {
 		return ""
 	}
-	if len(g.Candidates) > 0 && len(g.Candidates[0].Content.Parts) > 0 {
-		return g.Candidates[0].Content.Parts[0].Text
+	text, _ := g.Candidates[0].Content.getText()
+	return text
+}
+
+// getText returns the text of the parts, with the thought summaries apart.
+func (c *ChatContent) getText() (text string, thoughts string) {
+	var textBuilder, thoughtBuilder strings.Builder
+	for _, part := range c.Parts {
+		if part.Thought {
+			thoughtBuilder.WriteString(part.Text)
+		} else {
+			textBuilder.WriteString(part.Text)
+		}
 	}
-	return ""
+	return textBuilder.String(), thoughtBuilder.String()
 }
 
 type ChatCandidate struct {
@@ -218,24 +258,26 @@ type ChatPromptFeedback struct {
 func getToolCalls(candidate *ChatCandidate) []model.Tool {
 	var toolCalls []model.Tool
 
-	item := candidate.Content.Parts[0]
-	if item.FunctionCall == nil {
-		return toolCalls
-	}
-	argsBytes, err := json.Marshal(item.FunctionCall.Arguments)
-	if err != nil {
-		logger.FatalLog("getToolCalls failed: " + err.Error())
-		return toolCalls
-	}
-	toolCall := model.Tool{
-		Id:   fmt.Sprintf("call_%s", random.GetUUID()),
-		Type: "function",
-		Function: model.Function{
-			Arguments: string(argsBytes),
-			Name:      item.FunctionCall.FunctionName,
-		},
+	// thoughts may come before the call
+	for _, item := range candidate.Content.Parts {
+		if item.FunctionCall == nil {
+			continue
+		}
+		argsBytes, err := json.Marshal(item.FunctionCall.Arguments)
+		if err != nil {
+			logger.FatalLog("getToolCalls failed: " + err.Error())
+			return toolCalls
+		}
+		toolCall := model.Tool{
+			Id:   fmt.Sprintf("call_%s", random.GetUUID()),
+			Type: "function",
+			Function: model.Function{
+				Arguments: string(argsBytes),
+				Name:      item.FunctionCall.FunctionName,
+			},
+		}
+		toolCalls = append(toolCalls, toolCall)
 	}
-	toolCalls = append(toolCalls, toolCall)
 	return toolCalls
 }
 
@@ -255,17 +297,14 @@ func responseGeminiChat2OpenAI(response *ChatResponse) *openai.TextResponse {
 			FinishReason: constant.StopFinishReason,
 		}
 		if len(candidate.Content.Parts) > 0 {
-			if candidate.Content.Parts[0].FunctionCall != nil {
-				choice.Message.ToolCalls = getToolCalls(&candidate)
+			text, thoughts := candidate.Content.getText()
+			if thoughts != "" {
+				choice.Message.ReasoningContent = thoughts
+			}
+			if toolCalls := getToolCalls(&candidate); len(toolCalls) > 0 {
+				choice.Message.ToolCalls = toolCalls
 			} else {
-				var builder strings.Builder
-				for _, part := range candidate.Content.Parts {
-					if i > 0 {
-						builder.WriteString("\n")
-					}
-					builder.WriteString(part.Text)
-				}
-				choice.Message.Content = builder.String()
+				choice.Message.Content = text
 			}
 		} else {
 			choice.Message.Content = ""
@@ -278,7 +317,13 @@ func responseGeminiChat2OpenAI(response *ChatResponse) *openai.TextResponse {
 
 func streamResponseGeminiChat2OpenAI(geminiResponse *ChatResponse) *openai.ChatCompletionsStreamResponse {
 	var choice openai.ChatCompletionsStreamResponseChoice
-	choice.Delta.Content = geminiResponse.GetResponseText()
+	if len(geminiResponse.Candidates) > 0 {
+		text, thoughts := geminiResponse.Candidates[0].Content.getText()
+		choice.Delta.Content = text
+		if thoughts != "" {
+			choice.Delta.ReasoningContent = thoughts
+		}
+	}
 	//choice.FinishReason = &constant.StopFinishReason
 	var response openai.ChatCompletionsStreamResponse
 	response.Id = fmt.Sprintf("chatcmpl-%s", random.GetUUID())
@@ -306,8 +351,9 @@ func embeddingResponseGemini2OpenAI(response *EmbeddingResponse) *openai.Embeddi
 	return &openAIEmbeddingResponse
 }
 
-func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, string) {
+func StreamHandler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
 	responseText := ""
+	var usageMetadata *UsageMetadata
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Split(bufio.ScanLines)
 
@@ -328,6 +374,9 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 			logger.SysError("error unmarshalling stream response: " + err.Error())
 			continue
 		}
+		if geminiResponse.UsageMetadata != nil {
+			usageMetadata = geminiResponse.UsageMetadata
+		}
 
 		response := streamResponseGeminiChat2OpenAI(&geminiResponse)
 		if response == nil {
@@ -335,6 +384,9 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		}
 
 		responseText += response.Choices[0].Delta.StringContent()
+		if thoughts, ok := response.Choices[0].Delta.ReasoningContent.(string); ok {
+			responseText += thoughts
+		}
 
 		err = render.ObjectData(c, response)
 		if err != nil {
@@ -350,10 +402,13 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 
 	err := resp.Body.Close()
 	if err != nil {
-		return openai.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), ""
+		return openai.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
 	}
 
-	return nil, responseText
+	if usageMetadata != nil && usageMetadata.PromptTokenCount != 0 {
+		return nil, usageMetadata.ToUsage()
+	}
+	return nil, openai.ResponseText2Usage(responseText, modelName, promptTokens)
 }
 
 func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
@@ -383,11 +438,16 @@ func Handler(c *gin.Context, resp *http.Response, promptTokens int, modelName st
 	}
 	fullTextResponse := responseGeminiChat2OpenAI(&geminiResponse)
 	fullTextResponse.Model = modelName
-	completionTokens := openai.CountTokenText(geminiResponse.GetResponseText(), modelName)
-	usage := model.Usage{
-		PromptTokens:     promptTokens,
-		CompletionTokens: completionTokens,
-		TotalTokens:      promptTokens + completionTokens,
+	var usage model.Usage
+	if geminiResponse.UsageMetadata != nil && geminiResponse.UsageMetadata.PromptTokenCount != 0 {
+		usage = *geminiResponse.UsageMetadata.ToUsage()
+	} else {
+		completionTokens := openai.CountTokenText(geminiResponse.GetResponseText(), modelName)
+		usage = model.Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		}
 	}
 	fullTextResponse.Usage = usage
 	jsonResponse, err := json.Marshal(fullTextResponse)
@@ -1,5 +1,7 @@
 package gemini
 
+import "github.com/LeXwDeX/one-api/relay/model"
+
 type ChatRequest struct {
 	Contents          []ChatContent        `json:"contents"`
 	SafetySettings    []ChatSafetySettings `json:"safety_settings,omitempty"`
@@ -49,6 +51,7 @@ type Part struct {
 	Text         string        `json:"text,omitempty"`
 	InlineData   *InlineData   `json:"inlineData,omitempty"`
 	FunctionCall *FunctionCall `json:"functionCall,omitempty"`
+	Thought      bool          `json:"thought,omitempty"`
 }
 
 type ChatContent struct {
@@ -74,4 +77,112 @@ type ChatGenerationConfig struct {
 	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
 	CandidateCount   int      `json:"candidateCount,omitempty"`
 	StopSequences    []string `json:"stopSequences,omitempty"`
+	// ThinkingConfig is only accepted by the thinking models
+	ThinkingConfig *ThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+// https://ai.google.dev/gemini-api/docs/thinking
+type ThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
+// ToUsage converts the usage metadata into its OpenAI form; thoughts are billed as completion tokens.
+func (u *UsageMetadata) ToUsage() *model.Usage {
+	usage := &model.Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+		TotalTokens:      u.TotalTokenCount,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if u.CachedContentTokenCount != 0 {
+		usage.PromptTokensDetails = &model.PromptTokensDetails{
+			CachedTokens: u.CachedContentTokenCount,
+		}
+	}
+	if u.ThoughtsTokenCount != 0 {
+		usage.CompletionTokensDetails = &model.CompletionTokensDetails{
+			ReasoningTokens: u.ThoughtsTokenCount,
+		}
+	}
+	return usage
+}
+
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+// The types below describe requests arriving at the Gemini-native ingress, which use the
+// camelCase field names of the official SDKs and carry parts the adaptor never sends.
+
+type IngressRequest struct {
+	Contents          []IngressContent      `json:"contents"`
+	SystemInstruction *IngressContent       `json:"systemInstruction,omitempty"`
+	GenerationConfig  *ChatGenerationConfig `json:"generationConfig,omitempty"`
+	Tools             []IngressTool         `json:"tools,omitempty"`
+	ToolConfig        *IngressToolConfig    `json:"toolConfig,omitempty"`
+}
+
+type IngressContent struct {
+	Role  string        `json:"role,omitempty"`
+	Parts []IngressPart `json:"parts"`
+}
+
+type IngressPart struct {
+	Text             string            `json:"text,omitempty"`
+	InlineData       *InlineData       `json:"inlineData,omitempty"`
+	FileData         *FileData         `json:"fileData,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type FileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileUri  string `json:"fileUri"`
+}
+
+type FunctionResponse struct {
+	Name     string `json:"name"`
+	Response any    `json:"response"`
+}
+
+type IngressTool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+type FunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type IngressToolConfig struct {
+	FunctionCallingConfig *FunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type FunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type IngressEmbeddingRequest struct {
+	Model                string         `json:"model,omitempty"`
+	Content              IngressContent `json:"content"`
+	TaskType             string         `json:"taskType,omitempty"`
+	Title                string         `json:"title,omitempty"`
+	OutputDimensionality int            `json:"outputDimensionality,omitempty"`
+}
+
+type IngressEmbeddingResponse struct {
+	Embedding EmbeddingData `json:"embedding"`
 }
@@ -0,0 +1,110 @@
+package gemini
+
+import "strings"
+
+// schemaFields are the JSON Schema keywords the OpenAPI subset of responseSchema understands.
+// https://ai.google.dev/api/caching#Schema
+var schemaFields = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"maxItems":         true,
+	"minItems":         true,
+	"properties":       true,
+	"required":         true,
+	"minProperties":    true,
+	"maxProperties":    true,
+	"minLength":        true,
+	"maxLength":        true,
+	"pattern":          true,
+	"example":          true,
+	"anyOf":            true,
+	"propertyOrdering": true,
+	"default":          true,
+	"items":            true,
+	"minimum":          true,
+	"maximum":          true,
+}
+
+// maxSchemaDepth bounds the inlining of references, as recursive schemas cannot be expressed.
+const maxSchemaDepth = 16
+
+// convertSchema turns a JSON Schema as sent in response_format into a responseSchema: local
+// references are inlined, type unions with null become nullable, and unknown keywords, which
+// Gemini rejects, e.g. additionalProperties and $schema, are dropped.
+func convertSchema(schema map[string]any) map[string]any {
+	defs, _ := schema["$defs"].(map[string]any)
+	if defs == nil {
+		defs, _ = schema["definitions"].(map[string]any)
+	}
+	return convertSchemaNode(schema, defs, 0)
+}
+
+func convertSchemaNode(node map[string]any, defs map[string]any, depth int) map[string]any {
+	if ref, ok := node["$ref"].(string); ok && depth < maxSchemaDepth {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if def, ok := defs[name].(map[string]any); ok {
+			return convertSchemaNode(def, defs, depth+1)
+		}
+	}
+	result := make(map[string]any, len(node))
+	for key, value := range node {
+		switch {
+		case key == "type":
+			if types, ok := value.([]any); ok {
+				for _, t := range types {
+					if t == "null" {
+						result["nullable"] = true
+					} else if _, set := result["type"]; !set {
+						result["type"] = t
+					}
+				}
+				continue
+			}
+			result[key] = value
+		case key == "const":
+			result["enum"] = []any{value}
+		case key == "properties":
+			if properties, ok := value.(map[string]any); ok {
+				converted := make(map[string]any, len(properties))
+				for name, property := range properties {
+					if property, ok := property.(map[string]any); ok {
+						converted[name] = convertSchemaNode(property, defs, depth+1)
+					}
+				}
+				result[key] = converted
+			}
+		case key == "items":
+			if items, ok := value.(map[string]any); ok {
+				result[key] = convertSchemaNode(items, defs, depth+1)
+			}
+		case key == "anyOf" || key == "oneOf":
+			if variants, ok := value.([]any); ok {
+				converted := make([]any, 0, len(variants))
+				for _, variant := range variants {
+					if variant, ok := variant.(map[string]any); ok {
+						if variant["type"] == "null" {
+							result["nullable"] = true
+							continue
+						}
+						converted = append(converted, convertSchemaNode(variant, defs, depth+1))
+					}
+				}
+				if len(converted) == 1 {
+					// a single variant left once null is set aside
+					for k, v := range converted[0].(map[string]any) {
+						result[k] = v
+					}
+				} else {
+					result["anyOf"] = converted
+				}
+			}
+		case schemaFields[key]:
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertSchema(t *testing.T) {
+	schema := map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"city":    map[string]any{"$ref": "#/$defs/City"},
+			"note":    map[string]any{"type": []any{"string", "null"}},
+			"country": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+			"kind":    map[string]any{"const": "capital"},
+		},
+		"required": []any{"city"},
+		"$defs": map[string]any{
+			"City": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties":           map[string]any{"name": map[string]any{"type": "string"}},
+			},
+		},
+	}
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":    map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}},
+			"note":    map[string]any{"type": "string", "nullable": true},
+			"country": map[string]any{"type": "string", "nullable": true},
+			"kind":    map[string]any{"enum": []any{"capital"}},
+		},
+		"required": []any{"city"},
+	}, convertSchema(schema))
+}
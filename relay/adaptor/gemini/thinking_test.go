@@ -0,0 +1,72 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertRequestThinking(t *testing.T) {
+	effort := "medium"
+	textRequest := model.GeneralOpenAIRequest{
+		Model:           "gemini-2.5-flash",
+		Messages:        []model.Message{{Role: "user", Content: "why is the sky blue"}},
+		ReasoningEffort: &effort,
+	}
+	thinkingConfig := ConvertRequest(textRequest).GenerationConfig.ThinkingConfig
+	require.NotNil(t, thinkingConfig)
+	assert.Equal(t, 8192, *thinkingConfig.ThinkingBudget)
+	assert.True(t, thinkingConfig.IncludeThoughts)
+
+	effort = "none"
+	thinkingConfig = ConvertRequest(textRequest).GenerationConfig.ThinkingConfig
+	require.NotNil(t, thinkingConfig)
+	assert.Equal(t, 0, *thinkingConfig.ThinkingBudget)
+	assert.False(t, thinkingConfig.IncludeThoughts)
+
+	textRequest.ReasoningEffort = nil
+	assert.Nil(t, ConvertRequest(textRequest).GenerationConfig.ThinkingConfig)
+
+	// earlier models reject a thinking config
+	textRequest.ReasoningEffort = &effort
+	textRequest.Model = "gemini-2.0-flash"
+	assert.Nil(t, ConvertRequest(textRequest).GenerationConfig.ThinkingConfig)
+}
+
+func TestIsThinkingModel(t *testing.T) {
+	assert.True(t, isThinkingModel("gemini-2.5-pro"))
+	assert.True(t, isThinkingModel("gemini-3-pro-preview"))
+	assert.True(t, isThinkingModel("gemini-2.0-flash-thinking-exp-01-21"))
+	assert.False(t, isThinkingModel("gemini-2.0-flash"))
+	assert.False(t, isThinkingModel("gemini-1.5-pro"))
+	assert.False(t, isThinkingModel("gemma-3-27b-it"))
+}
+
+func TestResponseGeminiChat2OpenAIThoughts(t *testing.T) {
+	response := &ChatResponse{
+		Candidates: []ChatCandidate{{
+			Content: ChatContent{Role: "model", Parts: []Part{
+				{Text: "Light scatters.", Thought: true},
+				{Text: "Rayleigh scattering."},
+			}},
+		}},
+	}
+	choice := responseGeminiChat2OpenAI(response).Choices[0]
+	assert.Equal(t, "Rayleigh scattering.", choice.Message.Content)
+	assert.Equal(t, "Light scatters.", choice.Message.ReasoningContent)
+	assert.Equal(t, "Rayleigh scattering.", response.GetResponseText())
+
+	delta := streamResponseGeminiChat2OpenAI(response).Choices[0].Delta
+	assert.Equal(t, "Rayleigh scattering.", delta.Content)
+	assert.Equal(t, "Light scatters.", delta.ReasoningContent)
+}
+
+func TestUsageMetadataThoughts(t *testing.T) {
+	usage := (&UsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 5, ThoughtsTokenCount: 20, TotalTokenCount: 35}).ToUsage()
+	assert.Equal(t, 25, usage.CompletionTokens)
+	require.NotNil(t, usage.CompletionTokensDetails)
+	assert.Equal(t, 20, usage.CompletionTokensDetails.ReasoningTokens)
+}
@@ -19,3 +19,15 @@ type Adaptor interface {
 	GetModelList() []string
 	GetChannelName() string
 }
+
+// ImageEditor is implemented by adaptors whose channels edit images through their own API
+// instead of the multipart one of OpenAI.
+type ImageEditor interface {
+	ConvertImageEditRequest(relayMode int, request *model.ImageEditRequest) (any, error)
+}
+
+// Reranker is implemented by adaptors that relay /v1/rerank.
+type Reranker interface {
+	ConvertRerankRequest(request *model.RerankRequest) (any, error)
+	DoRerankResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (*model.RerankResponse, *model.ErrorWithStatusCode)
+}
@@ -35,6 +35,8 @@ func ConvertRequest(request model.GeneralOpenAIRequest) *ChatRequest {
 			NumCtx:           request.NumCtx,
 		},
 		Stream: request.Stream,
+		Format: convertResponseFormat(request.ResponseFormat),
+		Think:  convertReasoningEffort(request),
 	}
 	for _, message := range request.Messages {
 		openaiContent := message.ParseContent()
@@ -58,6 +60,69 @@ func ConvertRequest(request model.GeneralOpenAIRequest) *ChatRequest {
 	return &ollamaRequest
 }
 
+// convertResponseFormat returns the format Ollama constrains the output to.
+// https://github.com/ollama/ollama/blob/main/docs/api.md#request-structured-outputs
+func convertResponseFormat(responseFormat *model.ResponseFormat) any {
+	if responseFormat == nil {
+		return nil
+	}
+	switch responseFormat.Type {
+	case "json_object":
+		return "json"
+	case "json_schema":
+		if responseFormat.JsonSchema == nil || responseFormat.JsonSchema.Schema == nil {
+			return "json"
+		}
+		return responseFormat.JsonSchema.Schema
+	}
+	return nil
+}
+
+// thinkingModels are the model families Ollama can think with, it rejects think for the others.
+var thinkingModels = []string{"qwen3", "deepseek-r1", "deepseek-v3.1", "gpt-oss", "magistral"}
+
+func isThinkingModel(modelName string) bool {
+	for _, name := range thinkingModels {
+		if strings.Contains(modelName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertReasoningEffort returns whether the model should think before answering.
+// https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+func convertReasoningEffort(request model.GeneralOpenAIRequest) any {
+	budget, ok := request.GetThinkingBudget()
+	if !ok || !isThinkingModel(request.Model) {
+		return nil
+	}
+	if budget == 0 {
+		return false
+	}
+	if strings.HasPrefix(request.Model, "gpt-oss") {
+		// gpt-oss cannot turn thinking off, it only takes low, medium or high
+		if *request.ReasoningEffort == "minimal" {
+			return "low"
+		}
+		return *request.ReasoningEffort
+	}
+	return true
+}
+
+// setReasoningTokens estimates the completion tokens that went to thinking, which Ollama
+// does not count apart.
+func setReasoningTokens(usage *model.Usage, thinking string, modelName string) {
+	if thinking == "" {
+		return
+	}
+	reasoningTokens := openai.CountTokenText(thinking, modelName)
+	if reasoningTokens > usage.CompletionTokens {
+		reasoningTokens = usage.CompletionTokens
+	}
+	usage.CompletionTokensDetails = &model.CompletionTokensDetails{ReasoningTokens: reasoningTokens}
+}
+
 func responseOllama2OpenAI(response *ChatResponse) *openai.TextResponse {
 	choice := openai.TextResponseChoice{
 		Index: 0,
@@ -66,6 +131,9 @@ func responseOllama2OpenAI(response *ChatResponse) *openai.TextResponse {
 			Content: response.Message.Content,
 		},
 	}
+	if response.Message.Thinking != "" {
+		choice.Message.ReasoningContent = response.Message.Thinking
+	}
 	if response.Done {
 		choice.FinishReason = "stop"
 	}
@@ -81,6 +149,7 @@ func responseOllama2OpenAI(response *ChatResponse) *openai.TextResponse {
 			TotalTokens:      response.PromptEvalCount + response.EvalCount,
 		},
 	}
+	setReasoningTokens(&fullTextResponse.Usage, response.Message.Thinking, response.Model)
 	return &fullTextResponse
 }
 
@@ -88,6 +157,9 @@ func streamResponseOllama2OpenAI(ollamaResponse *ChatResponse) *openai.ChatCompl
 	var choice openai.ChatCompletionsStreamResponseChoice
 	choice.Delta.Role = ollamaResponse.Message.Role
 	choice.Delta.Content = ollamaResponse.Message.Content
+	if ollamaResponse.Message.Thinking != "" {
+		choice.Delta.ReasoningContent = ollamaResponse.Message.Thinking
+	}
 	if ollamaResponse.Done {
 		choice.FinishReason = &constant.StopFinishReason
 	}
@@ -103,6 +175,8 @@ func streamResponseOllama2OpenAI(ollamaResponse *ChatResponse) *openai.ChatCompl
 
 func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, *model.Usage) {
 	var usage model.Usage
+	var thinking strings.Builder
+	var modelName string
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		if atEOF && len(data) == 0 {
@@ -132,6 +206,8 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 			continue
 		}
 
+		thinking.WriteString(ollamaResponse.Message.Thinking)
+		modelName = ollamaResponse.Model
 		if ollamaResponse.EvalCount != 0 {
 			usage.PromptTokens = ollamaResponse.PromptEvalCount
 			usage.CompletionTokens = ollamaResponse.EvalCount
@@ -156,6 +232,7 @@ func StreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusC
 		return openai.ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
 	}
 
+	setReasoningTokens(&usage, thinking.String(), modelName)
 	return nil, &usage
 }
 
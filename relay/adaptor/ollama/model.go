@@ -12,15 +12,18 @@ type Options struct {
 }
 
 type Message struct {
-	Role    string   `json:"role,omitempty"`
-	Content string   `json:"content,omitempty"`
-	Images  []string `json:"images,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Content  string   `json:"content,omitempty"`
+	Thinking string   `json:"thinking,omitempty"`
+	Images   []string `json:"images,omitempty"`
 }
 
 type ChatRequest struct {
 	Model    string    `json:"model,omitempty"`
 	Messages []Message `json:"messages,omitempty"`
 	Stream   bool      `json:"stream"`
+	Format   any       `json:"format,omitempty"` // "json" or a JSON schema
+	Think    any       `json:"think,omitempty"`  // a bool, or an effort level for gpt-oss
 	Options  *Options  `json:"options,omitempty"`
 }
 
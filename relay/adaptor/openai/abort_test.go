@@ -0,0 +1,73 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common/client"
+	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+func TestStreamCancelledWithClient(t *testing.T) {
+	upstreamCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, word := range []string{"Hello", " world"} {
+			_, _ = fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(upstreamCancelled)
+	}))
+	defer server.Close()
+	client.HTTPClient = server.Client()
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader("{}")).WithContext(ctx)
+	m := &meta.Meta{
+		Mode:            relaymode.ChatCompletions,
+		ChannelType:     channeltype.OpenAI,
+		BaseURL:         server.URL,
+		RequestURLPath:  "/v1/chat/completions",
+		ActualModelName: "gpt-4o",
+		IsStream:        true,
+		PromptTokens:    5,
+	}
+	adaptor := &Adaptor{}
+	adaptor.Init(m)
+	resp, err := adaptor.DoRequest(c, m, strings.NewReader(`{"model":"gpt-4o","stream":true}`))
+	require.NoError(t, err)
+
+	// the client goes away once the first words arrived
+	resp.Body = &cancelOnRead{ReadCloser: resp.Body, cancel: cancel}
+	usage, respErr := adaptor.DoResponse(c, resp, m)
+	require.Nil(t, respErr)
+	<-upstreamCancelled
+	assert.Equal(t, 5, usage.PromptTokens)
+	assert.Positive(t, usage.CompletionTokens)
+}
+
+// cancelOnRead cancels the client request after the first read.
+type cancelOnRead struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnRead) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.cancel()
+	return n, err
+}
@@ -43,7 +43,7 @@ func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 
 		// https://learn.microsoft.com/en-us/azure/ai-services/openai
 		rawPath := strings.Split(meta.RequestURLPath, "?")[0]
-		if strings.HasPrefix(rawPath, "/v1/responses") {
+		if meta.Mode == relaymode.Responses {
 			// Azure Responses API: 模型部署名在 body.model，不走 deployments 段
 			requestURL := fmt.Sprintf("/openai/v1/responses?api-version=%s", meta.Config.APIVersion)
 			return GetFullRequestURL(meta.BaseURL, requestURL, meta.ChannelType), nil
@@ -230,43 +230,29 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
-	rawPath := strings.Split(meta.RequestURLPath, "?")[0]
-
 	if meta.IsStream {
-		if meta.ChannelType == channeltype.Azure && strings.HasPrefix(rawPath, "/v1/responses") {
-			var responseText string
-			var azureUsage *model.Usage
-			err, responseText, azureUsage = AzureResponsesStreamPassthrough(c, resp)
-			if azureUsage != nil && azureUsage.TotalTokens != 0 {
-				usage = azureUsage
-			} else {
-				usage = ResponseText2Usage(responseText, meta.ActualModelName, meta.PromptTokens)
-			}
-			if usage.TotalTokens != 0 && usage.PromptTokens == 0 { // some channels don't return prompt tokens & completion tokens
-				usage.PromptTokens = meta.PromptTokens
-				usage.CompletionTokens = usage.TotalTokens - meta.PromptTokens
-			}
-		} else {
-			var responseText string
+		var responseText string
+		switch meta.Mode {
+		case relaymode.Responses:
+			err, responseText, usage = ResponsesStreamHandler(c, resp)
+		default:
 			err, responseText, usage = StreamHandler(c, resp, meta.Mode)
-			if usage == nil || usage.TotalTokens == 0 {
-				usage = ResponseText2Usage(responseText, meta.ActualModelName, meta.PromptTokens)
-			}
-			if usage.TotalTokens != 0 && usage.PromptTokens == 0 { // some channels don't return prompt tokens & completion tokens
-				usage.PromptTokens = meta.PromptTokens
-				usage.CompletionTokens = usage.TotalTokens - meta.PromptTokens
-			}
+		}
+		if usage == nil || usage.TotalTokens == 0 {
+			usage = ResponseText2Usage(responseText, meta.ActualModelName, meta.PromptTokens)
+		}
+		if usage.TotalTokens != 0 && usage.PromptTokens == 0 { // some channels don't return prompt tokens & completion tokens
+			usage.PromptTokens = meta.PromptTokens
+			usage.CompletionTokens = usage.TotalTokens - meta.PromptTokens
 		}
 	} else {
-		if meta.ChannelType == channeltype.Azure && strings.HasPrefix(rawPath, "/v1/responses") {
-			err, usage = AzureResponsesNonStreamHandler(c, resp, meta.ActualModelName, meta.PromptTokens)
-		} else {
-			switch meta.Mode {
-			case relaymode.ImagesGenerations:
-				err, _ = ImageHandler(c, resp)
-			default:
-				err, usage = Handler(c, resp, meta.PromptTokens, meta.ActualModelName)
-			}
+		switch meta.Mode {
+		case relaymode.ImagesGenerations, relaymode.ImagesEdits, relaymode.ImagesVariations:
+			err, _ = ImageHandler(c, resp)
+		case relaymode.Responses:
+			err, usage = ResponsesHandler(c, resp, meta.PromptTokens, meta.ActualModelName)
+		default:
+			err, usage = Handler(c, resp, meta.PromptTokens, meta.ActualModelName)
 		}
 	}
 	return
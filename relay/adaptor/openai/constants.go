@@ -17,4 +17,6 @@ var ModelList = []string{
 	"gpt-5.1",
 	"gpt-5.1-codex",
 	"gpt-5.1-codex-mini",
+	"gpt-realtime",
+	"gpt-realtime-mini",
 }
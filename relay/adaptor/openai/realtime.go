@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor"
+	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// RealtimeEvent is a server event of the Realtime API, of which only response.done is read.
+// https://platform.openai.com/docs/api-reference/realtime-server-events/response/done
+type RealtimeEvent struct {
+	Type     string            `json:"type"`
+	Response *RealtimeResponse `json:"response,omitempty"`
+	Error    *model.Error      `json:"error,omitempty"`
+}
+
+type RealtimeResponse struct {
+	Usage *RealtimeUsage `json:"usage,omitempty"`
+}
+
+type RealtimeUsage struct {
+	TotalTokens        int                       `json:"total_tokens"`
+	InputTokens        int                       `json:"input_tokens"`
+	OutputTokens       int                       `json:"output_tokens"`
+	InputTokenDetails  RealtimeInputTokenDetails `json:"input_token_details"`
+	OutputTokenDetails RealtimeTokenDetails      `json:"output_token_details"`
+}
+
+type RealtimeInputTokenDetails struct {
+	CachedTokens        int                  `json:"cached_tokens"`
+	TextTokens          int                  `json:"text_tokens"`
+	AudioTokens         int                  `json:"audio_tokens"`
+	CachedTokensDetails RealtimeTokenDetails `json:"cached_tokens_details"`
+}
+
+type RealtimeTokenDetails struct {
+	TextTokens  int `json:"text_tokens"`
+	AudioTokens int `json:"audio_tokens"`
+}
+
+// GetRealtimeRequestURL returns the WebSocket URL of the Realtime API of the channel.
+func GetRealtimeRequestURL(meta *meta.Meta) (string, error) {
+	var fullRequestURL string
+	switch meta.ChannelType {
+	case channeltype.OpenAI:
+		requestURL := fmt.Sprintf("/v1/realtime?model=%s", url.QueryEscape(meta.ActualModelName))
+		fullRequestURL = GetFullRequestURL(meta.BaseURL, requestURL, meta.ChannelType)
+	case channeltype.Azure:
+		// https://learn.microsoft.com/en-us/azure/ai-services/openai/how-to/realtime-audio-websockets
+		requestURL := fmt.Sprintf("/openai/realtime?api-version=%s&deployment=%s", meta.Config.APIVersion, url.QueryEscape(meta.ActualModelName))
+		fullRequestURL = GetFullRequestURL(meta.BaseURL, requestURL, meta.ChannelType)
+	default:
+		return "", fmt.Errorf("realtime api is not supported by channel type %d", meta.ChannelType)
+	}
+	// https -> wss, http -> ws
+	if strings.HasPrefix(fullRequestURL, "http") {
+		fullRequestURL = "ws" + strings.TrimPrefix(fullRequestURL, "http")
+	}
+	return fullRequestURL, nil
+}
+
+// GetRealtimeRequestHeader authenticates the upstream handshake with the channel key; the
+// subprotocols of the client carry its own key and are not passed on.
+func GetRealtimeRequestHeader(c *gin.Context, meta *meta.Meta) http.Header {
+	header := http.Header{}
+	if meta.ChannelType == channeltype.Azure {
+		header.Set("api-key", meta.APIKey)
+	} else {
+		header.Set("Authorization", "Bearer "+meta.APIKey)
+	}
+	if beta := c.Request.Header.Get("OpenAI-Beta"); beta != "" {
+		header.Set("OpenAI-Beta", beta)
+	} else if strings.Contains(c.Request.Header.Get("Sec-WebSocket-Protocol"), "openai-beta.realtime-v1") {
+		header.Set("OpenAI-Beta", "realtime=v1")
+	}
+	adaptor.SetupChannelRequestHeader(c, header, meta)
+	return header
+}
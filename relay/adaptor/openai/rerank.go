@@ -0,0 +1,47 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// ConvertRerankRequest implements adaptor.Reranker. OpenAI-compatible channels, such as
+// SiliconFlow or a Jina endpoint, take the Jina-compatible request as is.
+func (a *Adaptor) ConvertRerankRequest(request *model.RerankRequest) (any, error) {
+	return request, nil
+}
+
+// DoRerankResponse implements adaptor.Reranker.
+func (a *Adaptor) DoRerankResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (*model.RerankResponse, *model.ErrorWithStatusCode) {
+	return RerankHandler(c, resp)
+}
+
+// RerankHandler passes the Jina-compatible response on and reads its usage.
+func RerankHandler(c *gin.Context, resp *http.Response) (*model.RerankResponse, *model.ErrorWithStatusCode) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	err = resp.Body.Close()
+	if err != nil {
+		return nil, ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError)
+	}
+	var rerankResponse model.RerankResponse
+	err = json.Unmarshal(responseBody, &rerankResponse)
+	if err != nil {
+		return nil, ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, err = c.Writer.Write(responseBody)
+	if err != nil {
+		return nil, ErrorWrapper(err, "write_response_body_failed", http.StatusInternalServerError)
+	}
+	return &rerankResponse, nil
+}
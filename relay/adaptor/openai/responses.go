@@ -0,0 +1,522 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/conv"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/random"
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+// https://platform.openai.com/docs/api-reference/responses
+
+// ConvertResponsesRequest translates a Responses API request into a chat completions request,
+// so that it can be served by adaptors which only speak chat completions.
+func ConvertResponsesRequest(request *model.ResponsesRequest) (*model.GeneralOpenAIRequest, error) {
+	if request == nil {
+		return nil, errors.New("request is nil")
+	}
+	textRequest := &model.GeneralOpenAIRequest{
+		Model:            request.Model,
+		MaxTokens:        request.MaxOutputTokens,
+		Temperature:      request.Temperature,
+		TopP:             request.TopP,
+		Stream:           request.Stream,
+		ParallelTooCalls: request.ParallelToolCalls,
+		User:             request.User,
+		ToolChoice:       convertResponsesToolChoice(request.ToolChoice),
+	}
+	if request.Reasoning != nil {
+		textRequest.ReasoningEffort = request.Reasoning.Effort
+	}
+	if request.Text != nil && request.Text.Format != nil {
+		switch request.Text.Format.Type {
+		case "json_schema":
+			textRequest.ResponseFormat = &model.ResponseFormat{
+				Type: "json_schema",
+				JsonSchema: &model.JSONSchema{
+					Name:        request.Text.Format.Name,
+					Description: request.Text.Format.Description,
+					Schema:      request.Text.Format.Schema,
+					Strict:      request.Text.Format.Strict,
+				},
+			}
+		case "json_object":
+			textRequest.ResponseFormat = &model.ResponseFormat{Type: "json_object"}
+		}
+	}
+	for _, tool := range request.Tools {
+		if tool.Type != "function" {
+			// built-in tools (web_search, file_search, ...) only exist upstream at OpenAI
+			continue
+		}
+		textRequest.Tools = append(textRequest.Tools, model.Tool{
+			Type: "function",
+			Function: model.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	if request.Instructions != "" {
+		textRequest.Messages = append(textRequest.Messages, model.Message{
+			Role:    role.System,
+			Content: request.Instructions,
+		})
+	}
+	messages, err := convertResponsesInput(request.Input)
+	if err != nil {
+		return nil, err
+	}
+	textRequest.Messages = append(textRequest.Messages, messages...)
+	return textRequest, nil
+}
+
+func convertResponsesToolChoice(toolChoice any) any {
+	choice, ok := toolChoice.(map[string]any)
+	if !ok {
+		return toolChoice
+	}
+	if choice["type"] == "function" {
+		if name, ok := choice["name"].(string); ok {
+			return map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name": name,
+				},
+			}
+		}
+	}
+	return toolChoice
+}
+
+func convertResponsesInput(input any) ([]model.Message, error) {
+	switch input := input.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []model.Message{{Role: role.User, Content: input}}, nil
+	}
+	jsonData, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var items []model.ResponsesInputItem
+	if err = json.Unmarshal(jsonData, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	var messages []model.Message
+	for _, item := range items {
+		switch item.Type {
+		case "", "message":
+			messageRole := item.Role
+			if messageRole == "developer" {
+				messageRole = role.System
+			}
+			messages = append(messages, model.Message{
+				Role:    messageRole,
+				Content: convertResponsesContent(item.Content),
+			})
+		case "function_call":
+			toolCall := model.Tool{
+				Id:   item.CallId,
+				Type: "function",
+				Function: model.Function{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+			// consecutive function calls belong to the same assistant turn
+			if len(messages) > 0 && messages[len(messages)-1].Role == role.Assistant && len(messages[len(messages)-1].ToolCalls) > 0 {
+				messages[len(messages)-1].ToolCalls = append(messages[len(messages)-1].ToolCalls, toolCall)
+				continue
+			}
+			messages = append(messages, model.Message{
+				Role:      role.Assistant,
+				Content:   "",
+				ToolCalls: []model.Tool{toolCall},
+			})
+		case "function_call_output":
+			output, ok := item.Output.(string)
+			if !ok {
+				outputData, _ := json.Marshal(item.Output)
+				output = string(outputData)
+			}
+			messages = append(messages, model.Message{
+				Role:       role.Tool,
+				Content:    output,
+				ToolCallId: item.CallId,
+			})
+		}
+	}
+	return messages, nil
+}
+
+func convertResponsesContent(content any) any {
+	parts, ok := content.([]any)
+	if !ok {
+		return content
+	}
+	var contents []any
+	for _, part := range parts {
+		partMap, ok := part.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch partMap["type"] {
+		case "input_text", "output_text", "text":
+			contents = append(contents, map[string]any{
+				"type": model.ContentTypeText,
+				"text": partMap["text"],
+			})
+		case "input_image":
+			imageURL := map[string]any{
+				"url": partMap["image_url"],
+			}
+			if detail, ok := partMap["detail"].(string); ok {
+				imageURL["detail"] = detail
+			}
+			contents = append(contents, map[string]any{
+				"type":      model.ContentTypeImageURL,
+				"image_url": imageURL,
+			})
+		}
+	}
+	return contents
+}
+
+func responsesStatus(finishReason string) (string, *model.ResponsesIncompleteDetails) {
+	if finishReason == "length" {
+		return "incomplete", &model.ResponsesIncompleteDetails{Reason: "max_output_tokens"}
+	}
+	if finishReason == "content_filter" {
+		return "incomplete", &model.ResponsesIncompleteDetails{Reason: "content_filter"}
+	}
+	return "completed", nil
+}
+
+// ResponseChat2Responses converts a chat completions response into a Responses API response.
+func ResponseChat2Responses(textResponse *TextResponse) *model.ResponsesResponse {
+	response := model.ResponsesResponse{
+		Id:        fmt.Sprintf("resp_%s", random.GetUUID()),
+		Object:    "response",
+		CreatedAt: textResponse.Created,
+		Model:     textResponse.Model,
+		Output:    make([]model.ResponsesOutputItem, 0),
+		Usage:     textResponse.Usage.ToResponsesUsage(),
+	}
+	if response.CreatedAt == 0 {
+		response.CreatedAt = helper.GetTimestamp()
+	}
+	var finishReason string
+	for _, choice := range textResponse.Choices {
+		if finishReason == "" {
+			finishReason = choice.FinishReason
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			response.Output = append(response.Output, model.ResponsesOutputItem{
+				Type:    "message",
+				Id:      fmt.Sprintf("msg_%s", random.GetUUID()),
+				Status:  "completed",
+				Role:    role.Assistant,
+				Content: []model.ResponsesOutputContent{{Type: "output_text", Text: text, Annotations: []any{}}},
+			})
+		}
+		for _, toolCall := range choice.Message.ToolCalls {
+			response.Output = append(response.Output, model.ResponsesOutputItem{
+				Type:      "function_call",
+				Id:        fmt.Sprintf("fc_%s", random.GetUUID()),
+				Status:    "completed",
+				CallId:    toolCall.Id,
+				Name:      toolCall.Function.Name,
+				Arguments: conv.AsString(toolCall.Function.Arguments),
+			})
+		}
+	}
+	response.Status, response.IncompleteDetails = responsesStatus(finishReason)
+	return &response
+}
+
+// ResponsesStreamEncoder re-encodes a chat completions stream as Responses API events.
+type ResponsesStreamEncoder struct {
+	response       model.ResponsesResponse
+	sequenceNumber int
+	started        bool
+	finishReason   string
+	usage          *model.Usage
+	// index of the output item currently receiving deltas, -1 if none
+	current int
+	// maps a chat tool call index to its output item index
+	toolCalls map[int]int
+}
+
+func NewResponsesStreamEncoder(modelName string) *ResponsesStreamEncoder {
+	return &ResponsesStreamEncoder{
+		response: model.ResponsesResponse{
+			Id:        fmt.Sprintf("resp_%s", random.GetUUID()),
+			Object:    "response",
+			CreatedAt: helper.GetTimestamp(),
+			Status:    "in_progress",
+			Model:     modelName,
+			Output:    make([]model.ResponsesOutputItem, 0),
+		},
+		current:   -1,
+		toolCalls: make(map[int]int),
+	}
+}
+
+func (e *ResponsesStreamEncoder) event(event model.ResponsesStreamEvent) render.Event {
+	event.SequenceNumber = e.sequenceNumber
+	e.sequenceNumber++
+	return render.Event{Event: event.Type, Data: event}
+}
+
+func (e *ResponsesStreamEncoder) snapshot() *model.ResponsesResponse {
+	response := e.response
+	response.Output = append([]model.ResponsesOutputItem(nil), e.response.Output...)
+	return &response
+}
+
+func (e *ResponsesStreamEncoder) start() []render.Event {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return []render.Event{
+		e.event(model.ResponsesStreamEvent{Type: "response.created", Response: e.snapshot()}),
+		e.event(model.ResponsesStreamEvent{Type: "response.in_progress", Response: e.snapshot()}),
+	}
+}
+
+func (e *ResponsesStreamEncoder) closeCurrent() []render.Event {
+	if e.current < 0 {
+		return nil
+	}
+	outputIndex := e.current
+	e.current = -1
+	item := &e.response.Output[outputIndex]
+	item.Status = "completed"
+	var events []render.Event
+	switch item.Type {
+	case "message":
+		contentIndex := 0
+		text := item.Content[0].Text
+		events = append(events,
+			e.event(model.ResponsesStreamEvent{Type: "response.output_text.done", ItemId: item.Id, OutputIndex: &outputIndex, ContentIndex: &contentIndex, Text: &text}),
+			e.event(model.ResponsesStreamEvent{Type: "response.content_part.done", ItemId: item.Id, OutputIndex: &outputIndex, ContentIndex: &contentIndex, Part: &item.Content[0]}),
+		)
+	case "function_call":
+		arguments := item.Arguments
+		events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.function_call_arguments.done", ItemId: item.Id, OutputIndex: &outputIndex, Arguments: &arguments}))
+	}
+	done := *item
+	events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.output_item.done", OutputIndex: &outputIndex, Item: &done}))
+	return events
+}
+
+func (e *ResponsesStreamEncoder) openItem(item model.ResponsesOutputItem) []render.Event {
+	events := e.closeCurrent()
+	e.response.Output = append(e.response.Output, item)
+	outputIndex := len(e.response.Output) - 1
+	e.current = outputIndex
+	added := item
+	events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.output_item.added", OutputIndex: &outputIndex, Item: &added}))
+	if item.Type == "message" {
+		contentIndex := 0
+		part := model.ResponsesOutputContent{Type: "output_text", Annotations: []any{}}
+		events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.content_part.added", ItemId: item.Id, OutputIndex: &outputIndex, ContentIndex: &contentIndex, Part: &part}))
+	}
+	return events
+}
+
+func (e *ResponsesStreamEncoder) EncodeStreamChunk(chunk *ChatCompletionsStreamResponse) []render.Event {
+	events := e.start()
+	if chunk.Model != "" && e.response.Model == "" {
+		e.response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			e.finishReason = *choice.FinishReason
+		}
+		if text := conv.AsString(choice.Delta.Content); text != "" {
+			if e.current < 0 || e.response.Output[e.current].Type != "message" {
+				events = append(events, e.openItem(model.ResponsesOutputItem{
+					Type:    "message",
+					Id:      fmt.Sprintf("msg_%s", random.GetUUID()),
+					Status:  "in_progress",
+					Role:    role.Assistant,
+					Content: []model.ResponsesOutputContent{{Type: "output_text", Annotations: []any{}}},
+				})...)
+			}
+			outputIndex := e.current
+			contentIndex := 0
+			item := &e.response.Output[outputIndex]
+			item.Content[0].Text += text
+			events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.output_text.delta", ItemId: item.Id, OutputIndex: &outputIndex, ContentIndex: &contentIndex, Delta: text}))
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			outputIndex, ok := -1, false
+			if toolCall.Index != nil {
+				outputIndex, ok = e.toolCalls[*toolCall.Index]
+			} else if toolCall.Id == "" && e.current >= 0 && e.response.Output[e.current].Type == "function_call" {
+				// adaptors without tool call indexes only send the id on the first delta
+				outputIndex, ok = e.current, true
+			}
+			if !ok {
+				events = append(events, e.openItem(model.ResponsesOutputItem{
+					Type:   "function_call",
+					Id:     fmt.Sprintf("fc_%s", random.GetUUID()),
+					Status: "in_progress",
+					CallId: toolCall.Id,
+					Name:   toolCall.Function.Name,
+				})...)
+				outputIndex = e.current
+				if toolCall.Index != nil {
+					e.toolCalls[*toolCall.Index] = outputIndex
+				}
+			}
+			arguments := conv.AsString(toolCall.Function.Arguments)
+			if arguments == "" {
+				continue
+			}
+			item := &e.response.Output[outputIndex]
+			item.Arguments += arguments
+			events = append(events, e.event(model.ResponsesStreamEvent{Type: "response.function_call_arguments.delta", ItemId: item.Id, OutputIndex: &outputIndex, Delta: arguments}))
+		}
+	}
+	return events
+}
+
+func (e *ResponsesStreamEncoder) EncodeStreamEnd(usage *model.Usage) []render.Event {
+	events := e.start()
+	events = append(events, e.closeCurrent()...)
+	if usage == nil {
+		usage = e.usage
+	}
+	e.response.Usage = usage.ToResponsesUsage()
+	e.response.Status, e.response.IncompleteDetails = responsesStatus(e.finishReason)
+	eventType := "response.completed"
+	if e.response.Status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	events = append(events, e.event(model.ResponsesStreamEvent{Type: eventType, Response: e.snapshot()}))
+	return events
+}
+
+func (e *ResponsesStreamEncoder) EncodeResponse(textResponse *TextResponse) any {
+	response := ResponseChat2Responses(textResponse)
+	if response.Model == "" {
+		response.Model = e.response.Model
+	}
+	return response
+}
+
+// ResponsesStreamHandler passes a native Responses API event stream through to the client,
+// collecting the output text and the usage reported in the final response event.
+func ResponsesStreamHandler(c *gin.Context, resp *http.Response) (*model.ErrorWithStatusCode, string, *model.Usage) {
+	responseText := ""
+	var usage *model.Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(bufio.ScanLines)
+
+	common.SetEventStreamHeaders(c)
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		_, _ = c.Writer.Write([]byte(line + "\n"))
+		if line == "" {
+			c.Writer.Flush()
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		var event model.ResponsesStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "response.output_text.delta":
+			responseText += event.Delta
+		case "response.completed", "response.incomplete", "response.failed":
+			if event.Response != nil && event.Response.Usage != nil {
+				usage = event.Response.Usage.ToUsage()
+			}
+		}
+	}
+	c.Writer.Flush()
+
+	if err := scanner.Err(); err != nil {
+		logger.SysError("error reading stream: " + err.Error())
+	}
+
+	if err := resp.Body.Close(); err != nil {
+		return ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), "", nil
+	}
+	return nil, responseText, usage
+}
+
+// ResponsesHandler forwards a native Responses API response to the client and extracts its usage.
+func ResponsesHandler(c *gin.Context, resp *http.Response, promptTokens int, modelName string) (*model.ErrorWithStatusCode, *model.Usage) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if err = resp.Body.Close(); err != nil {
+		return ErrorWrapper(err, "close_response_body_failed", http.StatusInternalServerError), nil
+	}
+	var response model.ResponsesResponse
+	if err = json.Unmarshal(responseBody, &response); err != nil {
+		return ErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError), nil
+	}
+	if response.Error != nil && response.Error.Message != "" {
+		return &model.ErrorWithStatusCode{
+			Error: model.Error{
+				Message: response.Error.Message,
+				Type:    "upstream_error",
+				Code:    response.Error.Code,
+			},
+			StatusCode: http.StatusInternalServerError,
+		}, nil
+	}
+
+	for k, v := range resp.Header {
+		c.Writer.Header().Set(k, v[0])
+	}
+	c.Writer.WriteHeader(resp.StatusCode)
+	if _, err = io.Copy(c.Writer, bytes.NewBuffer(responseBody)); err != nil {
+		return ErrorWrapper(err, "copy_response_body_failed", http.StatusInternalServerError), nil
+	}
+
+	if usage := response.Usage.ToUsage(); usage != nil && usage.TotalTokens != 0 {
+		return nil, usage
+	}
+	var responseText string
+	for _, item := range response.Output {
+		for _, content := range item.Content {
+			responseText += content.Text
+		}
+		responseText += item.Arguments
+	}
+	return nil, ResponseText2Usage(responseText, modelName, promptTokens)
+}
@@ -0,0 +1,89 @@
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/relay/constant/role"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestConvertResponsesRequest(t *testing.T) {
+	body := `{
+		"model": "gpt-4o",
+		"instructions": "be brief",
+		"max_output_tokens": 64,
+		"input": [
+			{"role": "user", "content": [{"type": "input_text", "text": "weather?"}]},
+			{"type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{}"},
+			{"type": "function_call_output", "call_id": "call_1", "output": "sunny"}
+		],
+		"tools": [
+			{"type": "function", "name": "get_weather", "parameters": {"type": "object"}},
+			{"type": "web_search"}
+		],
+		"tool_choice": {"type": "function", "name": "get_weather"}
+	}`
+	var request relaymodel.ResponsesRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &request))
+
+	textRequest, err := ConvertResponsesRequest(&request)
+	require.NoError(t, err)
+	assert.Equal(t, 64, textRequest.MaxTokens)
+	require.Len(t, textRequest.Tools, 1)
+	assert.Equal(t, "get_weather", textRequest.Tools[0].Function.Name)
+	assert.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}}, textRequest.ToolChoice)
+
+	require.Len(t, textRequest.Messages, 4)
+	assert.Equal(t, role.System, textRequest.Messages[0].Role)
+	assert.Equal(t, "weather?", textRequest.Messages[1].StringContent())
+	assert.Equal(t, role.Assistant, textRequest.Messages[2].Role)
+	assert.Equal(t, "call_1", textRequest.Messages[2].ToolCalls[0].Id)
+	assert.Equal(t, role.Tool, textRequest.Messages[3].Role)
+	assert.Equal(t, "call_1", textRequest.Messages[3].ToolCallId)
+}
+
+func TestResponsesStreamEncoder(t *testing.T) {
+	encoder := NewResponsesStreamEncoder("gpt-4o")
+	stop := "stop"
+	var events []string
+	for _, chunk := range []ChatCompletionsStreamResponse{
+		{Choices: []ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{Content: "Hel"}}}},
+		{Choices: []ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{Content: "lo"}}}},
+		{Choices: []ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Id: "call_1", Function: relaymodel.Function{Name: "f"}}}}}}},
+		{Choices: []ChatCompletionsStreamResponseChoice{{Delta: relaymodel.Message{ToolCalls: []relaymodel.Tool{{Function: relaymodel.Function{Arguments: "{}"}}}}, FinishReason: &stop}}},
+	} {
+		for _, event := range encoder.EncodeStreamChunk(&chunk) {
+			events = append(events, event.Event)
+		}
+	}
+	end := encoder.EncodeStreamEnd(&relaymodel.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+	for _, event := range end {
+		events = append(events, event.Event)
+	}
+	assert.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, events)
+
+	completed := end[len(end)-1].Data.(relaymodel.ResponsesStreamEvent).Response
+	require.Len(t, completed.Output, 2)
+	assert.Equal(t, "Hello", completed.Output[0].Content[0].Text)
+	assert.Equal(t, "{}", completed.Output[1].Arguments)
+	assert.Equal(t, 5, completed.Usage.TotalTokens)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/LeXwDeX/one-api/common/logger"
 	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
 	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/tokenizer"
 )
 
 // tokenEncoderMap won't grow after initialization
@@ -21,20 +22,12 @@ var defaultTokenEncoder *tiktoken.Tiktoken
 
 func InitTokenEncoders() {
 	logger.SysLog("initializing token encoders")
-	gpt35TokenEncoder, err := tiktoken.EncodingForModel("gpt-3.5-turbo")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-3.5-turbo token encoder: %s, "+
-			"if you are using in offline environment, please set TIKTOKEN_CACHE_DIR to use exsited files, check this link for more information: https://stackoverflow.com/questions/76106366/how-to-use-tiktoken-in-offline-mode-computer ", err.Error()))
-	}
+	// the encodings are read from the embedded tokenizer files, or TOKENIZER_DATA_DIR, never downloaded
+	tiktoken.SetBpeLoader(&tokenizer.BpeLoader{})
+	gpt35TokenEncoder := getInitialTokenEncoder("gpt-3.5-turbo")
 	defaultTokenEncoder = gpt35TokenEncoder
-	gpt4oTokenEncoder, err := tiktoken.EncodingForModel("gpt-4o")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-4o token encoder: %s", err.Error()))
-	}
-	gpt4TokenEncoder, err := tiktoken.EncodingForModel("gpt-4")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-4 token encoder: %s", err.Error()))
-	}
+	gpt4oTokenEncoder := getInitialTokenEncoder("gpt-4o")
+	gpt4TokenEncoder := getInitialTokenEncoder("gpt-4")
 	for model := range billingratio.ModelRatio {
 		if strings.HasPrefix(model, "gpt-3.5") {
 			tokenEncoderMap[model] = gpt35TokenEncoder
@@ -49,6 +42,17 @@ func InitTokenEncoders() {
 	logger.SysLog("token encoders initialized")
 }
 
+// getInitialTokenEncoder returns the encoder of the model, or nil when its encoding can't be
+// loaded, in which case the tokens are counted approximately.
+func getInitialTokenEncoder(model string) *tiktoken.Tiktoken {
+	tokenEncoder, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("failed to get %s token encoder, its tokens will be counted approximately: %s", model, err.Error()))
+		return nil
+	}
+	return tokenEncoder
+}
+
 func getTokenEncoder(model string) *tiktoken.Tiktoken {
 	tokenEncoder, ok := tokenEncoderMap[model]
 	if ok && tokenEncoder != nil {
@@ -78,8 +82,24 @@ func getTokenNum(tokenEncoder *tiktoken.Tiktoken, text string) int {
 	return len(tokenEncoder.Encode(text, nil, nil))
 }
 
-func CountTokenMessages(messages []model.Message, model string) int {
+// getTokenCounter returns how the text sent to the model is counted: with the tokenizer of its
+// family when one is registered, otherwise with tiktoken.
+func getTokenCounter(model string) func(text string) int {
+	if !config.ApproximateTokenEnabled {
+		if family := tokenizer.GetFamily(model); family != nil {
+			if familyTokenizer, ok := family.Tokenizer(); ok {
+				return familyTokenizer.Count
+			}
+		}
+	}
 	tokenEncoder := getTokenEncoder(model)
+	return func(text string) int {
+		return getTokenNum(tokenEncoder, text)
+	}
+}
+
+func CountTokenMessages(messages []model.Message, model string) int {
+	countText := getTokenCounter(model)
 	// Reference:
 	// https://github.com/openai/openai-cookbook/blob/main/examples/How_to_count_tokens_with_tiktoken.ipynb
 	// https://github.com/pkoukk/tiktoken-go/issues/6
@@ -99,7 +119,7 @@ func CountTokenMessages(messages []model.Message, model string) int {
 		tokenNum += tokensPerMessage
 		switch v := message.Content.(type) {
 		case string:
-			tokenNum += getTokenNum(tokenEncoder, v)
+			tokenNum += countText(v)
 		case []any:
 			for _, it := range v {
 				m := it.(map[string]any)
@@ -107,7 +127,7 @@ func CountTokenMessages(messages []model.Message, model string) int {
 				case "text":
 					if textValue, ok := m["text"]; ok {
 						if textString, ok := textValue.(string); ok {
-							tokenNum += getTokenNum(tokenEncoder, textString)
+							tokenNum += countText(textString)
 						}
 					}
 				case "image_url":
@@ -128,10 +148,10 @@ func CountTokenMessages(messages []model.Message, model string) int {
 				}
 			}
 		}
-		tokenNum += getTokenNum(tokenEncoder, message.Role)
+		tokenNum += countText(message.Role)
 		if message.Name != nil {
 			tokenNum += tokensPerName
-			tokenNum += getTokenNum(tokenEncoder, *message.Name)
+			tokenNum += countText(*message.Name)
 		}
 	}
 	tokenNum += 3 // Every reply is primed with <|start|>assistant<|message|>
@@ -153,6 +173,14 @@ const (
 func countImageTokens(url string, detail string, model string) (_ int, err error) {
 	var fetchSize = true
 	var width, height int
+	if family := tokenizer.GetFamily(model); family != nil && family.ImageTokens != nil {
+		// the family prices images by their size alone, whatever the detail
+		width, height, err = image.GetImageSize(url)
+		if err != nil {
+			return 0, err
+		}
+		return family.ImageTokens(width, height), nil
+	}
 	// Reference: https://platform.openai.com/docs/guides/vision/low-or-high-fidelity-image-understanding
 	// detail == "auto" is undocumented on how it works, it just said the model will use the auto setting which will look at the image input size and decide if it should use the low or high setting.
 	// According to the official guide, "low" disable the high-res model,
@@ -231,8 +259,7 @@ func CountTokenInput(input any, model string) int {
 }
 
 func CountTokenText(text string, model string) int {
-	tokenEncoder := getTokenEncoder(model)
-	return getTokenNum(tokenEncoder, text)
+	return getTokenCounter(model)(text)
 }
 
 func CountToken(text string) int {
@@ -0,0 +1,14 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitTokenEncodersOffline(t *testing.T) {
+	InitTokenEncoders()
+	assert.NotNil(t, defaultTokenEncoder)
+	assert.Equal(t, 2, CountTokenText("hello world", "gpt-4o"))
+	assert.Equal(t, 2, CountTokenText("hello world", "gpt-3.5-turbo"))
+}
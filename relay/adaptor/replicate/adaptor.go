@@ -39,6 +39,47 @@ func (*Adaptor) ConvertImageRequest(request *model.ImageRequest) (any, error) {
 	}, nil
 }
 
+// ConvertImageEditRequest implements adaptor.ImageEditor, turning an image edit into
+// an inpainting request of flux-fill.
+//
+// https://replicate.com/black-forest-labs/flux-fill-pro/api/schema
+func (*Adaptor) ConvertImageEditRequest(relayMode int, request *model.ImageEditRequest) (any, error) {
+	if relayMode != relaymode.ImagesEdits {
+		return nil, errors.New("replicate does not support image variations")
+	}
+	if !strings.Contains(request.Model, "flux-fill") {
+		return nil, errors.Errorf("model %s does not support image edits", request.Model)
+	}
+	img, err := readFormFile(request.Image[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "read image")
+	}
+	// like OpenAI, the transparent area of the image is edited when there is no mask
+	maskSource := img
+	if request.Mask != nil {
+		maskSource, err = readFormFile(request.Mask)
+		if err != nil {
+			return nil, errors.Wrap(err, "read mask")
+		}
+	}
+	mask, err := convertMask(maskSource)
+	if err != nil {
+		return nil, errors.Wrap(err, "convert mask")
+	}
+	return InpaintingImageByFlusReplicateRequest{
+		Input: FluxInpaintingInput{
+			Mask:            toDataURL(mask),
+			Image:           toDataURL(img),
+			Seed:            int(time.Now().UnixNano()),
+			Steps:           50,
+			Prompt:          request.Prompt,
+			Guidance:        60,
+			OutputFormat:    "png",
+			SafetyTolerance: 2,
+		},
+	}, nil
+}
+
 func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.GeneralOpenAIRequest) (any, error) {
 	if !request.Stream {
 		// TODO: support non-stream mode
@@ -105,6 +146,8 @@ func (a *Adaptor) GetRequestURL(meta *meta.Meta) (string, error) {
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Request, meta *meta.Meta) error {
 	adaptor.SetupCommonRequestHeader(c, req, meta)
+	// image edits come in as multipart, but replicate always takes json
+	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+meta.APIKey)
 	return nil
 }
@@ -116,7 +159,7 @@ func (a *Adaptor) DoRequest(c *gin.Context, meta *meta.Meta, requestBody io.Read
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
 	switch meta.Mode {
-	case relaymode.ImagesGenerations:
+	case relaymode.ImagesGenerations, relaymode.ImagesEdits:
 		err, usage = ImageHandler(c, resp)
 	case relaymode.ChatCompletions:
 		err, usage = ChatHandler(c, resp)
@@ -46,7 +46,7 @@ type FluxInpaintingInput struct {
 	Seed             int    `json:"seed"`
 	Steps            int    `json:"steps" binding:"required,min=1"`
 	Prompt           string `json:"prompt" binding:"required,min=5"`
-	Guidance         int    `json:"guidance" binding:"required,min=2,max=5"`
+	Guidance         int    `json:"guidance" binding:"required,min=2,max=100"`
 	OutputFormat     string `json:"output_format"`
 	SafetyTolerance  int    `json:"safety_tolerance" binding:"required,min=1,max=5"`
 	PromptUnsampling bool   `json:"prompt_unsampling"`
@@ -33,4 +33,6 @@ var ModelList = []string{
 	"Pro/internlm/internlm2_5-7b-chat",
 	"Pro/meta-llama/Meta-Llama-3-8B-Instruct",
 	"Pro/mistralai/Mistral-7B-Instruct-v0.2",
+	"BAAI/bge-reranker-v2-m3",
+	"netease-youdao/bce-reranker-base_v1",
 }
@@ -0,0 +1,123 @@
+package adaptor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/relay/meta"
+)
+
+var (
+	ErrFirstTokenTimeout = errors.New("upstream sent nothing within the first token timeout")
+	ErrIdleTimeout       = errors.New("upstream stalled longer than the idle timeout")
+)
+
+// IsTimeout tells whether the error is one of the relay timeouts, which are worth retrying
+// on another channel as long as nothing has been sent to the client.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrFirstTokenTimeout) || errors.Is(err, ErrIdleTimeout)
+}
+
+func getTimeouts(meta *meta.Meta) (firstToken time.Duration, idle time.Duration) {
+	firstTokenTimeout := config.RelayFirstTokenTimeout
+	if meta.Config.FirstTokenTimeout > 0 {
+		firstTokenTimeout = meta.Config.FirstTokenTimeout
+	}
+	idleTimeout := config.RelayIdleTimeout
+	if meta.Config.IdleTimeout > 0 {
+		idleTimeout = meta.Config.IdleTimeout
+	}
+	return time.Duration(firstTokenTimeout) * time.Second, time.Duration(idleTimeout) * time.Second
+}
+
+// watchdog cancels the upstream request once the current timeout elapses: the first token
+// timeout until the first bytes of the body arrived, then the idle timeout between reads.
+type watchdog struct {
+	sync.Mutex
+	timer  *time.Timer
+	idle   time.Duration
+	cancel context.CancelCauseFunc
+}
+
+func (w *watchdog) arm(timeout time.Duration, cause error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if timeout > 0 {
+		w.timer = time.AfterFunc(timeout, func() {
+			w.cancel(cause)
+		})
+	}
+}
+
+func (w *watchdog) stop() {
+	w.arm(0, nil)
+	w.cancel(context.Canceled)
+}
+
+type watchdogBody struct {
+	io.Reader
+	body     io.Closer
+	ctx      context.Context
+	watchdog *watchdog
+}
+
+func (b *watchdogBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if n > 0 {
+		b.watchdog.arm(b.watchdog.idle, ErrIdleTimeout)
+	}
+	if err != nil && err != io.EOF {
+		if cause := context.Cause(b.ctx); IsTimeout(cause) {
+			err = cause
+		}
+	}
+	return n, err
+}
+
+func (b *watchdogBody) Close() error {
+	b.watchdog.stop()
+	return b.body.Close()
+}
+
+// DoRequestWithTimeouts does the request under the first token and idle timeouts of the channel.
+// It returns once the first bytes of the body arrived, so that a first token timeout is returned
+// as an error before anything is relayed to the client.
+func DoRequestWithTimeouts(c *gin.Context, req *http.Request, meta *meta.Meta) (*http.Response, error) {
+	firstTokenTimeout, idleTimeout := getTimeouts(meta)
+	if firstTokenTimeout == 0 && idleTimeout == 0 {
+		return DoRequest(c, req)
+	}
+	ctx, cancel := context.WithCancelCause(req.Context())
+	w := &watchdog{idle: idleTimeout, cancel: cancel}
+	w.arm(firstTokenTimeout, ErrFirstTokenTimeout)
+	resp, err := DoRequest(c, req.WithContext(ctx))
+	if err == nil {
+		reader := bufio.NewReader(resp.Body)
+		resp.Body = &watchdogBody{Reader: reader, body: resp.Body, ctx: ctx, watchdog: w}
+		if _, err = reader.Peek(1); err == io.EOF {
+			err = nil
+		}
+		if err == nil {
+			w.arm(idleTimeout, ErrIdleTimeout)
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+	}
+	if cause := context.Cause(ctx); IsTimeout(cause) {
+		err = cause
+	}
+	w.stop()
+	return nil, err
+}
@@ -0,0 +1,62 @@
+package adaptor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common/client"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/meta"
+)
+
+func TestDoRequestWithTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall-after-first" {
+			_, _ = io.WriteString(w, "data: {}\n\n")
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	client.HTTPClient = server.Client()
+
+	gin.SetMode(gin.TestMode)
+	m := &meta.Meta{Config: model.ChannelConfig{FirstTokenTimeout: 1, IdleTimeout: 1}}
+	newRequest := func(path string) (*gin.Context, *http.Request) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, server.URL+path, strings.NewReader("{}"))
+		require.NoError(t, err)
+		return c, req
+	}
+
+	// the headers alone are no token
+	c, req := newRequest("/silent")
+	start := time.Now()
+	_, err := DoRequestWithTimeouts(c, req, m)
+	assert.ErrorIs(t, err, ErrFirstTokenTimeout)
+	assert.True(t, IsTimeout(err))
+	assert.Less(t, time.Since(start), 3*time.Second)
+
+	c, req = newRequest("/stall-after-first")
+	resp, err := DoRequestWithTimeouts(c, req, m)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.Equal(t, "data: {}\n\n", string(body))
+	assert.ErrorIs(t, err, ErrIdleTimeout)
+	_ = resp.Body.Close()
+}
@@ -43,6 +43,8 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 		TopK:        claudeReq.TopK,
 		Stream:      claudeReq.Stream,
 		Tools:       claudeReq.Tools,
+		ToolChoice:  claudeReq.ToolChoice,
+		Thinking:    claudeReq.Thinking,
 	}
 
 	c.Set(ctxkey.RequestModel, request.Model)
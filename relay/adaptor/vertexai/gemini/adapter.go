@@ -7,7 +7,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/LeXwDeX/one-api/common/ctxkey"
 	"github.com/LeXwDeX/one-api/relay/adaptor/gemini"
-	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
 	"github.com/LeXwDeX/one-api/relay/relaymode"
 
 	"github.com/LeXwDeX/one-api/relay/meta"
@@ -40,9 +39,7 @@ func (a *Adaptor) ConvertRequest(c *gin.Context, relayMode int, request *model.G
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, meta *meta.Meta) (usage *model.Usage, err *model.ErrorWithStatusCode) {
 	if meta.IsStream {
-		var responseText string
-		err, responseText = gemini.StreamHandler(c, resp)
-		usage = openai.ResponseText2Usage(responseText, meta.ActualModelName, meta.PromptTokens)
+		err, usage = gemini.StreamHandler(c, resp, meta.PromptTokens, meta.ActualModelName)
 	} else {
 		switch meta.Mode {
 		case relaymode.Embeddings:
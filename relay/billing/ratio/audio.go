@@ -0,0 +1,93 @@
+package ratio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+var audioRatioLock sync.RWMutex
+
+// AudioPromptRatio is the price of an audio prompt token relative to a text prompt token
+// of the same model. Cached audio tokens are billed as cached text tokens.
+// https://platform.openai.com/docs/pricing#audio-tokens
+var AudioPromptRatio = map[string]float64{
+	"gpt-4o-realtime-preview":      40.0 / 5,
+	"gpt-4o-mini-realtime-preview": 10.0 / 0.6,
+	"gpt-realtime":                 32.0 / 4,
+	"gpt-realtime-mini":            10.0 / 0.6,
+}
+
+// AudioCompletionRatio is the price of an audio completion token relative to a text
+// completion token of the same model.
+var AudioCompletionRatio = map[string]float64{
+	"gpt-4o-realtime-preview":      80.0 / 20,
+	"gpt-4o-mini-realtime-preview": 20.0 / 2.4,
+	"gpt-realtime":                 64.0 / 16,
+	"gpt-realtime-mini":            20.0 / 2.4,
+}
+
+func AudioPromptRatio2JSONString() string {
+	audioRatioLock.RLock()
+	defer audioRatioLock.RUnlock()
+	jsonBytes, err := json.Marshal(AudioPromptRatio)
+	if err != nil {
+		logger.SysError("error marshalling audio prompt ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateAudioPromptRatioByJSONString(jsonStr string) error {
+	audioRatioLock.Lock()
+	defer audioRatioLock.Unlock()
+	AudioPromptRatio = make(map[string]float64)
+	return json.Unmarshal([]byte(jsonStr), &AudioPromptRatio)
+}
+
+func AudioCompletionRatio2JSONString() string {
+	audioRatioLock.RLock()
+	defer audioRatioLock.RUnlock()
+	jsonBytes, err := json.Marshal(AudioCompletionRatio)
+	if err != nil {
+		logger.SysError("error marshalling audio completion ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateAudioCompletionRatioByJSONString(jsonStr string) error {
+	audioRatioLock.Lock()
+	defer audioRatioLock.Unlock()
+	AudioCompletionRatio = make(map[string]float64)
+	return json.Unmarshal([]byte(jsonStr), &AudioCompletionRatio)
+}
+
+// lookupAudioRatio also matches dated snapshots, e.g. gpt-4o-realtime-preview-2024-12-17,
+// by the longest model name they start with.
+func lookupAudioRatio(ratios map[string]float64, name string, channelType int) float64 {
+	audioRatioLock.RLock()
+	defer audioRatioLock.RUnlock()
+	if ratio, ok := ratios[fmt.Sprintf("%s(%d)", name, channelType)]; ok {
+		return ratio
+	}
+	if ratio, ok := ratios[name]; ok {
+		return ratio
+	}
+	ratio, matched := 1.0, ""
+	for model, r := range ratios {
+		if strings.HasPrefix(name, model) && len(model) > len(matched) {
+			ratio, matched = r, model
+		}
+	}
+	return ratio
+}
+
+func GetAudioPromptRatio(name string, channelType int) float64 {
+	return lookupAudioRatio(AudioPromptRatio, name, channelType)
+}
+
+func GetAudioCompletionRatio(name string, channelType int) float64 {
+	return lookupAudioRatio(AudioCompletionRatio, name, channelType)
+}
@@ -0,0 +1,103 @@
+package ratio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+var cacheRatioLock sync.RWMutex
+
+// CacheReadRatio is the price of a prompt token served from the upstream prompt cache,
+// relative to an uncached prompt token of the same model.
+// https://platform.openai.com/docs/guides/prompt-caching
+// https://docs.anthropic.com/en/docs/build-with-claude/prompt-caching#pricing
+// https://ai.google.dev/gemini-api/docs/caching
+var CacheReadRatio = map[string]float64{
+	"deepseek-chat":     0.014 / 0.14,
+	"deepseek-reasoner": 0.14 / 0.55,
+}
+
+// CacheWriteRatio is the price of a prompt token written to the upstream prompt cache,
+// relative to an uncached prompt token of the same model.
+var CacheWriteRatio = map[string]float64{}
+
+func CacheReadRatio2JSONString() string {
+	cacheRatioLock.RLock()
+	defer cacheRatioLock.RUnlock()
+	jsonBytes, err := json.Marshal(CacheReadRatio)
+	if err != nil {
+		logger.SysError("error marshalling cache read ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateCacheReadRatioByJSONString(jsonStr string) error {
+	cacheRatioLock.Lock()
+	defer cacheRatioLock.Unlock()
+	CacheReadRatio = make(map[string]float64)
+	return json.Unmarshal([]byte(jsonStr), &CacheReadRatio)
+}
+
+func CacheWriteRatio2JSONString() string {
+	cacheRatioLock.RLock()
+	defer cacheRatioLock.RUnlock()
+	jsonBytes, err := json.Marshal(CacheWriteRatio)
+	if err != nil {
+		logger.SysError("error marshalling cache write ratio: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateCacheWriteRatioByJSONString(jsonStr string) error {
+	cacheRatioLock.Lock()
+	defer cacheRatioLock.Unlock()
+	CacheWriteRatio = make(map[string]float64)
+	return json.Unmarshal([]byte(jsonStr), &CacheWriteRatio)
+}
+
+func lookupCacheRatio(ratios map[string]float64, name string, channelType int) (float64, bool) {
+	cacheRatioLock.RLock()
+	defer cacheRatioLock.RUnlock()
+	if ratio, ok := ratios[fmt.Sprintf("%s(%d)", name, channelType)]; ok {
+		return ratio, true
+	}
+	ratio, ok := ratios[name]
+	return ratio, ok
+}
+
+func GetCacheReadRatio(name string, channelType int) float64 {
+	if ratio, ok := lookupCacheRatio(CacheReadRatio, name, channelType); ok {
+		return ratio
+	}
+	switch {
+	case strings.HasPrefix(name, "claude-"):
+		return 0.1
+	case strings.HasPrefix(name, "gemini-"):
+		return 0.25
+	case strings.HasPrefix(name, "gpt-5"), strings.HasPrefix(name, "gpt-realtime"):
+		return 0.1
+	case strings.HasPrefix(name, "gpt-4o"), strings.HasPrefix(name, "chatgpt-4o"),
+		strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "o3-mini"):
+		return 0.5
+	case strings.HasPrefix(name, "gpt-4.1"), strings.HasPrefix(name, "o3"), strings.HasPrefix(name, "o4"):
+		return 0.25
+	case strings.HasPrefix(name, "deepseek-"):
+		return 0.1
+	}
+	return 1
+}
+
+func GetCacheWriteRatio(name string, channelType int) float64 {
+	if ratio, ok := lookupCacheRatio(CacheWriteRatio, name, channelType); ok {
+		return ratio
+	}
+	if strings.HasPrefix(name, "claude-") {
+		// 5-minute cache writes
+		return 1.25
+	}
+	return 1
+}
@@ -92,6 +92,11 @@ var ModelRatio = map[string]float64{
 	"text-moderation-latest":  0.1,
 	"dall-e-2":                0.02 * USD, // $0.016 - $0.020 / image
 	"dall-e-3":                0.04 * USD, // $0.040 - $0.120 / image
+	// realtime models, audio tokens are weighed by the audio ratios
+	"gpt-4o-realtime-preview":      2.5, // $5.00 / 1M text input tokens
+	"gpt-4o-mini-realtime-preview": 0.3, // $0.60 / 1M text input tokens
+	"gpt-realtime":                 2,   // $4.00 / 1M text input tokens
+	"gpt-realtime-mini":            0.3, // $0.60 / 1M text input tokens
 	// https://docs.anthropic.com/en/docs/about-claude/models
 	"claude-instant-1.2":         0.8 / 1000 * USD,
 	"claude-2.0":                 8.0 / 1000 * USD,
@@ -339,6 +344,10 @@ var ModelRatio = map[string]float64{
 	"command-light-nightly": 0.5,
 	"command-r":             0.5 / 1000 * USD,
 	"command-r-plus":        3.0 / 1000 * USD,
+	// rerank models are billed per search unit, priced like an image
+	"rerank-v3.5":              2.0 / 1000 * USD, // $2.00 / 1K searches
+	"rerank-english-v3.0":      2.0 / 1000 * USD,
+	"rerank-multilingual-v3.0": 2.0 / 1000 * USD,
 	// https://platform.deepseek.com/api-docs/pricing/
 	"deepseek-chat":     0.14 * MILLI_USD,
 	"deepseek-reasoner": 0.55 * MILLI_USD,
@@ -635,6 +644,9 @@ var CompletionRatio = map[string]float64{
 	// deepseek
 	"deepseek-chat":     0.28 / 0.14,
 	"deepseek-reasoner": 2.19 / 0.55,
+	// openai realtime, text tokens
+	"gpt-realtime":      16.0 / 4,
+	"gpt-realtime-mini": 2.4 / 0.6,
 }
 
 var (
@@ -2,5 +2,7 @@ package role
 
 const (
 	System    = "system"
+	User      = "user"
 	Assistant = "assistant"
+	Tool      = "tool"
 )
@@ -13,11 +13,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/LeXwDeX/one-api/common"
-	"github.com/LeXwDeX/one-api/common/client"
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/ctxkey"
 	"github.com/LeXwDeX/one-api/common/logger"
 	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/adaptor"
 	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
 	"github.com/LeXwDeX/one-api/relay/billing"
 	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
@@ -114,6 +114,7 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	if modelMapping != nil && modelMapping[audioModel] != "" {
 		audioModel = modelMapping[audioModel]
 	}
+	meta.ActualModelName = audioModel
 
 	baseURL := channeltype.ChannelBaseURLs[channelType]
 	requestURL := c.Request.URL.String()
@@ -141,7 +142,7 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody.Bytes()))
 	responseFormat := c.DefaultPostForm("response_format", "json")
 
-	req, err := http.NewRequest(c.Request.Method, fullRequestURL, requestBody)
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, fullRequestURL, requestBody)
 	if err != nil {
 		return openai.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
 	}
@@ -157,10 +158,11 @@ func RelayAudioHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	}
 	req.Header.Set("Content-Type", c.Request.Header.Get("Content-Type"))
 	req.Header.Set("Accept", c.Request.Header.Get("Accept"))
+	adaptor.SetupChannelRequestHeader(c, req.Header, meta)
 
-	resp, err := client.HTTPClient.Do(req)
+	resp, err := adaptor.DoRequestWithTimeouts(c, req, meta)
 	if err != nil {
-		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		return wrapDoRequestError(err)
 	}
 
 	err = req.Body.Close()
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/LeXwDeX/one-api/common/client"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/storage"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+// channelKeepsFiles tells whether the channel only accepts files by the ids of its own Files API.
+func channelKeepsFiles(channelType int) bool {
+	return channelType == channeltype.OpenAI || channelType == channeltype.Azure
+}
+
+func getUpstreamFilesURL(meta *meta.Meta, path string) string {
+	if meta.ChannelType == channeltype.Azure {
+		return fmt.Sprintf("%s/openai/files%s?api-version=%s", meta.BaseURL, path, meta.Config.APIVersion)
+	}
+	return openai.GetFullRequestURL(meta.BaseURL, "/v1/files"+path, meta.ChannelType)
+}
+
+func doUpstreamFileRequest(ctx context.Context, meta *meta.Meta, method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, getUpstreamFilesURL(meta, path), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if meta.ChannelType == channeltype.Azure {
+		req.Header.Set("api-key", meta.APIKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+meta.APIKey)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, errors.Errorf("upstream returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return resp, nil
+}
+
+// uploadUpstreamFile mirrors the file to the channel's Files API and returns the id it was given there.
+func uploadUpstreamFile(ctx context.Context, meta *meta.Meta, file *model.File) (string, error) {
+	content, err := storage.Default().Get(ctx, file.StorageKey)
+	if err != nil {
+		return "", errors.Wrap(err, "open file")
+	}
+	defer content.Close()
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+	go func() {
+		err := writer.WriteField("purpose", file.Purpose)
+		if err == nil {
+			var part io.Writer
+			part, err = writer.CreateFormFile("file", file.Filename)
+			if err == nil {
+				_, err = io.Copy(part, content)
+			}
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+	resp, err := doUpstreamFileRequest(ctx, meta, http.MethodPost, "", writer.FormDataContentType(), pipeReader)
+	// unblocks the writer if the request gave up before reading the whole body
+	pipeReader.Close()
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var upstreamFile struct {
+		Id string `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&upstreamFile)
+	if err != nil {
+		return "", errors.Wrap(err, "decode upstream file")
+	}
+	if upstreamFile.Id == "" {
+		return "", errors.New("upstream file has no id")
+	}
+	return upstreamFile.Id, nil
+}
+
+// getUpstreamFileId returns the id of the file's copy on the channel, mirroring it on first use.
+func getUpstreamFileId(ctx context.Context, meta *meta.Meta, file *model.File) (string, error) {
+	keyFingerprint := model.KeyFingerprint(meta.APIKey)
+	if upstreamFileId := file.GetUpstreamFileId(meta.ChannelId, keyFingerprint); upstreamFileId != "" {
+		return upstreamFileId, nil
+	}
+	upstreamFileId, err := uploadUpstreamFile(ctx, meta, file)
+	if err != nil {
+		return "", err
+	}
+	logger.Infof(ctx, "file %s mirrored to channel #%d as %s", file.FileId, meta.ChannelId, upstreamFileId)
+	err = file.SetUpstreamFileId(meta.ChannelId, keyFingerprint, upstreamFileId)
+	if err != nil {
+		logger.Errorf(ctx, "failed to save upstream file id: %s", err.Error())
+	}
+	return upstreamFileId, nil
+}
+
+// DeleteUpstreamFiles removes the copies of the file mirrored to channels. Failures are only logged,
+// as the upstream copies are useless once the file is gone.
+func DeleteUpstreamFiles(ctx context.Context, file *model.File) {
+	for _, upstreamFile := range file.GetUpstreamFileIds() {
+		channel, err := model.GetChannelById(upstreamFile.ChannelId, true)
+		if err != nil {
+			continue
+		}
+		// the copy can only be deleted with the key it was uploaded with
+		channelMeta := meta.GetByChannel(channel)
+		channelMeta.APIKey = ""
+		for _, key := range channel.GetKeys() {
+			if model.KeyFingerprint(key) == upstreamFile.KeyFingerprint {
+				channelMeta.APIKey = key
+				break
+			}
+		}
+		if channelMeta.APIKey == "" {
+			logger.Warnf(ctx, "key of file %s on channel #%d was removed, leaving it there", upstreamFile.FileId, upstreamFile.ChannelId)
+			continue
+		}
+		resp, err := doUpstreamFileRequest(ctx, channelMeta, http.MethodDelete, "/"+upstreamFile.FileId, "", nil)
+		if err != nil {
+			logger.Warnf(ctx, "failed to delete file %s from channel #%d: %s", upstreamFile.FileId, upstreamFile.ChannelId, err.Error())
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
+func readFileContent(ctx context.Context, file *model.File) ([]byte, error) {
+	content, err := storage.Default().Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	return io.ReadAll(content)
+}
+
+// resolveFileReferences rewrites the message parts that refer to files uploaded through the Files API.
+// Channels keeping their own files get the id of a mirrored copy, the others get the content inline.
+func resolveFileReferences(ctx context.Context, meta *meta.Meta, textRequest *relaymodel.GeneralOpenAIRequest) *relaymodel.ErrorWithStatusCode {
+	for i := range textRequest.Messages {
+		parts, ok := textRequest.Messages[i].Content.([]any)
+		if !ok {
+			continue
+		}
+		for j, part := range parts {
+			partMap, ok := part.(map[string]any)
+			if !ok || partMap["type"] != relaymodel.ContentTypeFile {
+				continue
+			}
+			fileMap, ok := partMap["file"].(map[string]any)
+			if !ok {
+				continue
+			}
+			fileId, _ := fileMap["file_id"].(string)
+			if fileId == "" {
+				continue
+			}
+			file, err := model.GetFileByFileId(fileId, meta.UserId)
+			if errors.Is(err, gorm.ErrRecordNotFound) && channelKeepsFiles(meta.ChannelType) {
+				// uploaded straight to the upstream, which knows the id
+				continue
+			}
+			if err != nil {
+				return openai.ErrorWrapper(fmt.Errorf("file %s not found", fileId), "file_not_found", http.StatusBadRequest)
+			}
+			if channelKeepsFiles(meta.ChannelType) {
+				upstreamFileId, err := getUpstreamFileId(ctx, meta, file)
+				if err != nil {
+					return openai.ErrorWrapper(err, "upload_upstream_file_failed", http.StatusBadGateway)
+				}
+				fileMap["file_id"] = upstreamFileId
+				continue
+			}
+			content, err := readFileContent(ctx, file)
+			if err != nil {
+				return openai.ErrorWrapper(err, "read_file_failed", http.StatusInternalServerError)
+			}
+			dataURL := fmt.Sprintf("data:%s;base64,%s", file.ContentType, base64.StdEncoding.EncodeToString(content))
+			if strings.HasPrefix(file.ContentType, "image/") {
+				parts[j] = map[string]any{
+					"type":      relaymodel.ContentTypeImageURL,
+					"image_url": map[string]any{"url": dataURL},
+				}
+				continue
+			}
+			parts[j] = map[string]any{
+				"type": relaymodel.ContentTypeFile,
+				"file": map[string]any{
+					"filename":  file.Filename,
+					"file_data": dataURL,
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// hasFileParts tells whether any message carries a file part, in which case the request body
+// has to be rebuilt from the parsed request.
+func hasFileParts(textRequest *relaymodel.GeneralOpenAIRequest) bool {
+	for _, message := range textRequest.Messages {
+		parts, ok := message.Content.([]any)
+		if !ok {
+			continue
+		}
+		for _, part := range parts {
+			if partMap, ok := part.(map[string]any); ok && partMap["type"] == relaymodel.ContentTypeFile {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestResolveUnknownFileReferences(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.File{}))
+	saved := model.DB
+	model.DB = db
+	defer func() { model.DB = saved }()
+
+	newRequest := func() *relaymodel.GeneralOpenAIRequest {
+		return &relaymodel.GeneralOpenAIRequest{Messages: []relaymodel.Message{{
+			Role: "user",
+			Content: []any{map[string]any{
+				"type": relaymodel.ContentTypeFile,
+				"file": map[string]any{"file_id": "file-upstream"},
+			}},
+		}}}
+	}
+
+	// files uploaded straight to OpenAI are left to the upstream
+	textRequest := newRequest()
+	assert.Nil(t, resolveFileReferences(context.Background(), &meta.Meta{UserId: 1, ChannelType: channeltype.OpenAI}, textRequest))
+	assert.Equal(t, newRequest(), textRequest)
+
+	errWithStatusCode := resolveFileReferences(context.Background(), &meta.Meta{UserId: 1, ChannelType: channeltype.Anthropic}, newRequest())
+	require.NotNil(t, errWithStatusCode)
+	assert.Equal(t, http.StatusBadRequest, errWithStatusCode.StatusCode)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/relay/adaptor/gemini"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/controller/validator"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// https://ai.google.dev/api/generate-content
+
+// parseGeminiAction splits the path parameter of /v1beta/models/{model}:{action}.
+func parseGeminiAction(c *gin.Context) (modelName string, action string) {
+	modelName, action, _ = strings.Cut(c.Param("model"), ":")
+	return modelName, action
+}
+
+// RelayGeminiHelper serves a Gemini-native request through the chat completions
+// or embeddings of any adaptor.
+func RelayGeminiHelper(c *gin.Context, relayMode int) *model.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	modelName, action := parseGeminiAction(c)
+	if modelName == "" {
+		return openai.ErrorWrapper(errors.New("model is required"), "invalid_gemini_request", http.StatusBadRequest)
+	}
+	if relayMode == relaymode.GeminiEmbedContent {
+		embeddingRequest := &gemini.IngressEmbeddingRequest{}
+		if err := common.UnmarshalBodyReusable(c, embeddingRequest); err != nil {
+			logger.Errorf(ctx, "unmarshal embedContent request failed: %s", err.Error())
+			return openai.ErrorWrapper(err, "invalid_gemini_request", http.StatusBadRequest)
+		}
+		textRequest := gemini.ConvertIngressEmbeddingRequest(embeddingRequest, modelName)
+		if err := validator.ValidateTextRequest(textRequest, relaymode.Embeddings); err != nil {
+			return openai.ErrorWrapper(err, "invalid_gemini_request", http.StatusBadRequest)
+		}
+		return relayIngressEmbeddingRequest(c, meta, textRequest, gemini.EncodeEmbeddingResponse)
+	}
+
+	var stream bool
+	switch action {
+	case "generateContent":
+	case "streamGenerateContent":
+		stream = true
+	default:
+		return openai.ErrorWrapper(fmt.Errorf("unsupported action: %s", action), "invalid_gemini_request", http.StatusNotFound)
+	}
+	// without alt=sse, streamGenerateContent answers with a JSON array of chunks
+	jsonArray := stream && c.Query("alt") != "sse"
+	generateRequest := &gemini.IngressRequest{}
+	if err := common.UnmarshalBodyReusable(c, generateRequest); err != nil {
+		logger.Errorf(ctx, "unmarshal generateContent request failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_gemini_request", http.StatusBadRequest)
+	}
+	if len(generateRequest.Contents) == 0 {
+		return openai.ErrorWrapper(errors.New("field contents is required"), "invalid_gemini_request", http.StatusBadRequest)
+	}
+	textRequest, err := gemini.ConvertIngressRequest(generateRequest, modelName, stream && !jsonArray)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_gemini_request", http.StatusBadRequest)
+	}
+	if err = validator.ValidateTextRequest(textRequest, relaymode.ChatCompletions); err != nil {
+		return openai.ErrorWrapper(err, "invalid_gemini_request", http.StatusBadRequest)
+	}
+	return relayIngressRequest(c, meta, textRequest, gemini.NewIngressStreamEncoder(modelName, jsonArray))
+}
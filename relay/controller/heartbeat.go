@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/render"
+)
+
+// heartbeatWriter writes SSE heartbeats to the client whenever the stream has been silent for an
+// interval, e.g. while a reasoning model thinks before its first token. The heartbeats are written
+// from their own goroutine, so the writes are serialized and the headers are kept apart until the
+// response is committed.
+type heartbeatWriter struct {
+	gin.ResponseWriter
+	mu        sync.Mutex
+	header    http.Header
+	lastWrite time.Time
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// startStreamHeartbeat installs a heartbeatWriter on the context when enabled for the request,
+// and returns the function that removes it.
+func startStreamHeartbeat(c *gin.Context, isStream bool) (stop func()) {
+	if !isStream || config.StreamHeartbeatInterval <= 0 {
+		return func() {}
+	}
+	w := newHeartbeatWriter(c.Writer, time.Duration(config.StreamHeartbeatInterval)*time.Second)
+	c.Writer = w
+	return func() {
+		w.stop()
+		c.Writer = w.ResponseWriter
+	}
+}
+
+func newHeartbeatWriter(writer gin.ResponseWriter, interval time.Duration) *heartbeatWriter {
+	w := &heartbeatWriter{
+		ResponseWriter: writer,
+		header:         writer.Header().Clone(),
+		lastWrite:      time.Now(),
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+	}
+	go w.run(interval)
+	return w
+}
+
+func (w *heartbeatWriter) run(interval time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if time.Since(w.lastWrite) >= interval {
+				if !w.ResponseWriter.Written() {
+					header := w.ResponseWriter.Header()
+					header.Set("Content-Type", "text/event-stream")
+					header.Set("Cache-Control", "no-cache")
+					header.Set("Connection", "keep-alive")
+					header.Set("X-Accel-Buffering", "no")
+					w.ResponseWriter.WriteHeader(http.StatusOK)
+				}
+				if render.Heartbeat(w.ResponseWriter) != nil {
+					// the client is gone
+					w.mu.Unlock()
+					return
+				}
+				w.lastWrite = time.Now()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *heartbeatWriter) stop() {
+	close(w.done)
+	<-w.stopped
+}
+
+// commit hands the headers set so far to the response, unless a heartbeat committed it already.
+func (w *heartbeatWriter) commit() {
+	if w.ResponseWriter.Written() {
+		return
+	}
+	header := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+}
+
+func (w *heartbeatWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *heartbeatWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ResponseWriter.Written() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *heartbeatWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.commit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *heartbeatWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.commit()
+	w.lastWrite = time.Now()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *heartbeatWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *heartbeatWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseWriter.Flush()
+}
+
+func (w *heartbeatWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Written()
+}
+
+func (w *heartbeatWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+func (w *heartbeatWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Size()
+}
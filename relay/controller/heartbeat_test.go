@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/render"
+)
+
+func TestHeartbeatWriter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	w := newHeartbeatWriter(c.Writer, 20*time.Millisecond)
+	c.Writer = w
+
+	// the upstream thinks for a while
+	time.Sleep(100 * time.Millisecond)
+	common.SetEventStreamHeaders(c)
+	render.StringData(c, `{"choices":[]}`)
+	render.Done(c)
+	w.stop()
+
+	body := recorder.Body.String()
+	assert.True(t, strings.HasPrefix(body, ": keepalive\n\n"), body)
+	assert.True(t, strings.HasSuffix(body, "data: {\"choices\":[]}\n\ndata: [DONE]\n\n"), body)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+}
+
+func TestHeartbeatWriterQuiet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	w := newHeartbeatWriter(c.Writer, time.Hour)
+	c.Writer = w
+
+	// a response that came in time is left untouched
+	c.JSON(400, gin.H{"error": "bad"})
+	w.stop()
+	assert.Equal(t, 400, recorder.Code)
+	assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `{"error":"bad"}`, recorder.Body.String())
+}
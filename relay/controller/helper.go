@@ -17,12 +17,14 @@ import (
 	"github.com/LeXwDeX/one-api/common/config"
 	"github.com/LeXwDeX/one-api/common/logger"
 	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/adaptor"
 	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
 	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
 	"github.com/LeXwDeX/one-api/relay/channeltype"
 	"github.com/LeXwDeX/one-api/relay/controller/validator"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
 	"github.com/LeXwDeX/one-api/relay/relaymode"
 )
 
@@ -101,13 +103,19 @@ func postConsumeQuota(ctx context.Context, usage *relaymodel.Usage, meta *meta.M
 	}
 	var quota int64
 	completionRatio := billingratio.GetCompletionRatio(textRequest.Model, meta.ChannelType)
+	cacheReadRatio := billingratio.GetCacheReadRatio(textRequest.Model, meta.ChannelType)
+	cacheWriteRatio := billingratio.GetCacheWriteRatio(textRequest.Model, meta.ChannelType)
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
-	quota = int64(math.Ceil((float64(promptTokens) + float64(completionTokens)*completionRatio) * ratio))
+	cachedTokens := usage.GetCachedTokens()
+	cacheWriteTokens := usage.GetCacheWriteTokens()
+	billedPromptTokens := getBilledPromptTokens(promptTokens, cachedTokens, cacheWriteTokens, cacheReadRatio, cacheWriteRatio)
+	quota = int64(math.Ceil((billedPromptTokens + float64(completionTokens)*completionRatio) * ratio))
 	if ratio != 0 && quota <= 0 {
 		quota = 1
 	}
 	totalTokens := promptTokens + completionTokens
+	ratelimit.RecordTokens(ctx, meta.RateLimitScopes, totalTokens)
 	if totalTokens == 0 {
 		// in this case, must be some error happened
 		// we cannot just return, because we may have to return the pre-consumed quota
@@ -123,21 +131,57 @@ func postConsumeQuota(ctx context.Context, usage *relaymodel.Usage, meta *meta.M
 		logger.Error(ctx, "error update user quota cache: "+err.Error())
 	}
 	logContent := fmt.Sprintf("倍率：%.2f × %.2f × %.2f", modelRatio, groupRatio, completionRatio)
+	if cachedTokens > 0 {
+		logContent += fmt.Sprintf("，缓存读取 %d tokens × %.2f", cachedTokens, cacheReadRatio)
+	}
+	if cacheWriteTokens > 0 {
+		logContent += fmt.Sprintf("，缓存写入 %d tokens × %.2f", cacheWriteTokens, cacheWriteRatio)
+	}
+	if meta.IsBatch {
+		logContent += fmt.Sprintf("，批处理 × %.2f", config.BatchRatio)
+	}
+	if meta.IsResponseCacheHit {
+		logContent += fmt.Sprintf("，响应缓存命中 × %.2f", config.ResponseCacheHitRatio)
+	}
+	if meta.IsClientAborted {
+		logContent += "，客户端中断"
+	}
+	requestedModel := ""
+	if meta.IsFallback {
+		requestedModel = meta.OriginModelName
+	}
 	model.RecordConsumeLog(ctx, &model.Log{
 		UserId:            meta.UserId,
 		ChannelId:         meta.ChannelId,
 		PromptTokens:      promptTokens,
 		CompletionTokens:  completionTokens,
+		CachedTokens:      cachedTokens,
+		CacheWriteTokens:  cacheWriteTokens,
 		ModelName:         textRequest.Model,
+		RequestedModel:    requestedModel,
 		TokenName:         meta.TokenName,
 		Quota:             int(quota),
 		Content:           logContent,
 		IsStream:          meta.IsStream,
 		ElapsedTime:       helper.CalcElapsedTime(meta.StartTime),
 		SystemPromptReset: systemPromptReset,
+		ResponseCacheHit:  meta.IsResponseCacheHit,
+		ClientAborted:     meta.IsClientAborted,
 	})
 	model.UpdateUserUsedQuotaAndRequestCount(meta.UserId, quota)
-	model.UpdateChannelUsedQuota(meta.ChannelId, quota)
+	if !meta.IsResponseCacheHit {
+		model.UpdateChannelUsedQuota(meta.ChannelId, quota)
+	}
+}
+
+// getBilledPromptTokens weighs the prompt tokens served from or written to the prompt cache
+// by their cache ratios; the rest are billed as usual.
+func getBilledPromptTokens(promptTokens int, cachedTokens int, cacheWriteTokens int, cacheReadRatio float64, cacheWriteRatio float64) float64 {
+	uncachedTokens := promptTokens - cachedTokens - cacheWriteTokens
+	if uncachedTokens < 0 {
+		uncachedTokens = 0
+	}
+	return float64(uncachedTokens) + float64(cachedTokens)*cacheReadRatio + float64(cacheWriteTokens)*cacheWriteRatio
 }
 
 func getMappedModelName(modelName string, mapping map[string]string) (string, bool) {
@@ -196,3 +240,12 @@ func setSystemPrompt(ctx context.Context, request *relaymodel.GeneralOpenAIReque
 	logger.Infof(ctx, "add system prompt")
 	return true
 }
+
+// wrapDoRequestError wraps the error of adaptor.DoRequest, telling timeouts apart so that
+// they read as such to the client and to the retry logic.
+func wrapDoRequestError(err error) *relaymodel.ErrorWithStatusCode {
+	if adaptor.IsTimeout(err) {
+		return openai.ErrorWrapper(err, "upstream_timeout", http.StatusGatewayTimeout)
+	}
+	return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+}
@@ -0,0 +1,17 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBilledPromptTokens(t *testing.T) {
+	assert.Equal(t, 1000.0, getBilledPromptTokens(1000, 0, 0, 0.1, 1.25))
+	// 200 uncached + 800 × 0.1 cached
+	assert.InDelta(t, 280.0, getBilledPromptTokens(1000, 800, 0, 0.1, 1.25), 1e-9)
+	// 100 uncached + 100 × 0.1 cached + 800 × 1.25 written
+	assert.InDelta(t, 1110.0, getBilledPromptTokens(1000, 100, 800, 0.1, 1.25), 1e-9)
+	// inconsistent upstream usage never yields negative uncached tokens
+	assert.InDelta(t, 50.0, getBilledPromptTokens(100, 500, 0, 0.1, 1), 1e-9)
+}
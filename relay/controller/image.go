@@ -7,7 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -16,13 +19,19 @@ import (
 	"github.com/LeXwDeX/one-api/common/logger"
 	"github.com/LeXwDeX/one-api/model"
 	"github.com/LeXwDeX/one-api/relay"
+	"github.com/LeXwDeX/one-api/relay/adaptor"
 	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/apitype"
 	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
 	"github.com/LeXwDeX/one-api/relay/channeltype"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
 )
 
+// imageEditFormMaxMemory is how much of the multipart form is kept in memory, as in net/http
+const imageEditFormMaxMemory = 32 << 20
+
 func getImageRequest(c *gin.Context, _ int) (*relaymodel.ImageRequest, error) {
 	imageRequest := &relaymodel.ImageRequest{}
 	err := common.UnmarshalBodyReusable(c, imageRequest)
@@ -41,6 +50,156 @@ func getImageRequest(c *gin.Context, _ int) (*relaymodel.ImageRequest, error) {
 	return imageRequest, nil
 }
 
+func isImageEditMode(relayMode int) bool {
+	return relayMode == relaymode.ImagesEdits || relayMode == relaymode.ImagesVariations
+}
+
+func getMultipartBoundary(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	if mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", errors.New("request must be multipart/form-data")
+	}
+	return params["boundary"], nil
+}
+
+func getFormValue(form *multipart.Form, key string) string {
+	if values := form.Value[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// getImageEditRequest parses the multipart form of an image edit or variation. The form is read
+// from the cached body, so the request body is left for the retries.
+func getImageEditRequest(c *gin.Context) (*relaymodel.ImageEditRequest, error) {
+	requestBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+	boundary, err := getMultipartBoundary(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	form, err := multipart.NewReader(bytes.NewReader(requestBody), boundary).ReadForm(imageEditFormMaxMemory)
+	if err != nil {
+		return nil, err
+	}
+	imageEditRequest := &relaymodel.ImageEditRequest{
+		ImageRequest: relaymodel.ImageRequest{
+			Model:          getFormValue(form, "model"),
+			Prompt:         getFormValue(form, "prompt"),
+			Size:           getFormValue(form, "size"),
+			Quality:        getFormValue(form, "quality"),
+			ResponseFormat: getFormValue(form, "response_format"),
+			User:           getFormValue(form, "user"),
+		},
+		Image: append(form.File["image"], form.File["image[]"]...),
+		Form:  form,
+	}
+	if masks := form.File["mask"]; len(masks) > 0 {
+		imageEditRequest.Mask = masks[0]
+	}
+	if n := getFormValue(form, "n"); n != "" {
+		imageEditRequest.N, err = strconv.Atoi(n)
+		if err != nil {
+			_ = form.RemoveAll()
+			return nil, fmt.Errorf("invalid value of n: %s", n)
+		}
+	}
+	if len(imageEditRequest.Image) == 0 {
+		_ = form.RemoveAll()
+		return nil, errors.New("image is required")
+	}
+	if imageEditRequest.N == 0 {
+		imageEditRequest.N = 1
+	}
+	if imageEditRequest.Size == "" {
+		imageEditRequest.Size = "1024x1024"
+	}
+	if imageEditRequest.Model == "" {
+		imageEditRequest.Model = "dall-e-2"
+	}
+	return imageEditRequest, nil
+}
+
+// getImageEditRequestBody converts the image edit for adaptors that edit images their own way.
+// OpenAI-compatible channels get the form as is, rewritten with the same boundary when the
+// model name changed, so that the Content-Type of the client still applies.
+func getImageEditRequestBody(c *gin.Context, a adaptor.Adaptor, meta *meta.Meta, imageEditRequest *relaymodel.ImageEditRequest) (io.Reader, *relaymodel.ErrorWithStatusCode) {
+	if editor, ok := a.(adaptor.ImageEditor); ok {
+		finalRequest, err := editor.ConvertImageEditRequest(meta.Mode, imageEditRequest)
+		if err != nil {
+			return nil, openai.ErrorWrapper(err, "convert_image_request_failed", http.StatusBadRequest)
+		}
+		jsonStr, err := json.Marshal(finalRequest)
+		if err != nil {
+			return nil, openai.ErrorWrapper(err, "marshal_image_request_failed", http.StatusInternalServerError)
+		}
+		return bytes.NewBuffer(jsonStr), nil
+	}
+	if meta.APIType != apitype.OpenAI {
+		return nil, openai.ErrorWrapper(fmt.Errorf("image edits are not supported by channel type %d", meta.ChannelType), "image_edits_not_supported", http.StatusBadRequest)
+	}
+	if getFormValue(imageEditRequest.Form, "model") == imageEditRequest.Model {
+		return c.Request.Body, nil
+	}
+	boundary, err := getMultipartBoundary(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "invalid_image_request", http.StatusBadRequest)
+	}
+	requestBody, err := rewriteImageEditForm(imageEditRequest.Form, boundary, imageEditRequest.Model)
+	if err != nil {
+		return nil, openai.ErrorWrapper(err, "rewrite_image_request_failed", http.StatusInternalServerError)
+	}
+	return requestBody, nil
+}
+
+func rewriteImageEditForm(form *multipart.Form, boundary string, modelName string) (*bytes.Buffer, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteField("model", modelName); err != nil {
+		return nil, err
+	}
+	for key, values := range form.Value {
+		if key == "model" {
+			continue
+		}
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, files := range form.File {
+		for _, fileHeader := range files {
+			part, err := writer.CreatePart(fileHeader.Header)
+			if err != nil {
+				return nil, err
+			}
+			file, err := fileHeader.Open()
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(part, file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
 func isValidImageSize(model string, size string) bool {
 	if model == "cogview-3" || billingratio.ImageSizeRatios[model] == nil {
 		return true
@@ -66,9 +225,9 @@ func getImageSizeRatio(model string, size string) float64 {
 	return 1
 }
 
-func validateImageRequest(imageRequest *relaymodel.ImageRequest, _ *meta.Meta) *relaymodel.ErrorWithStatusCode {
-	// check prompt length
-	if imageRequest.Prompt == "" {
+func validateImageRequest(imageRequest *relaymodel.ImageRequest, meta *meta.Meta) *relaymodel.ErrorWithStatusCode {
+	// check prompt length, variations take none
+	if imageRequest.Prompt == "" && meta.Mode != relaymode.ImagesVariations {
 		return openai.ErrorWrapper(errors.New("prompt is required"), "prompt_missing", http.StatusBadRequest)
 	}
 
@@ -106,7 +265,18 @@ func getImageCostRatio(imageRequest *relaymodel.ImageRequest) (float64, error) {
 func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatusCode {
 	ctx := c.Request.Context()
 	meta := meta.GetByContext(c)
-	imageRequest, err := getImageRequest(c, meta.Mode)
+	var imageRequest *relaymodel.ImageRequest
+	var imageEditRequest *relaymodel.ImageEditRequest
+	var err error
+	if isImageEditMode(meta.Mode) {
+		imageEditRequest, err = getImageEditRequest(c)
+		if err == nil {
+			defer imageEditRequest.Form.RemoveAll()
+			imageRequest = &imageEditRequest.ImageRequest
+		}
+	} else {
+		imageRequest, err = getImageRequest(c, meta.Mode)
+	}
 	if err != nil {
 		logger.Errorf(ctx, "getImageRequest failed: %s", err.Error())
 		return openai.ErrorWrapper(err, "invalid_image_request", http.StatusBadRequest)
@@ -135,7 +305,7 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	c.Set("response_format", imageRequest.ResponseFormat)
 
 	var requestBody io.Reader
-	if isModelMapped || meta.ChannelType == channeltype.Azure { // make Azure channel request body
+	if imageEditRequest == nil && (isModelMapped || meta.ChannelType == channeltype.Azure) { // make Azure channel request body
 		jsonStr, err := json.Marshal(imageRequest)
 		if err != nil {
 			return openai.ErrorWrapper(err, "marshal_image_request_failed", http.StatusInternalServerError)
@@ -152,11 +322,16 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	adaptor.Init(meta)
 
 	// these adaptors need to convert the request
-	switch meta.ChannelType {
-	case channeltype.Zhipu,
-		channeltype.Ali,
-		channeltype.Replicate,
-		channeltype.Baidu:
+	switch {
+	case imageEditRequest != nil:
+		requestBody, bizErr = getImageEditRequestBody(c, adaptor, meta, imageEditRequest)
+		if bizErr != nil {
+			return bizErr
+		}
+	case meta.ChannelType == channeltype.Zhipu,
+		meta.ChannelType == channeltype.Ali,
+		meta.ChannelType == channeltype.Replicate,
+		meta.ChannelType == channeltype.Baidu:
 		finalRequest, err := adaptor.ConvertImageRequest(imageRequest)
 		if err != nil {
 			return openai.ErrorWrapper(err, "convert_image_request_failed", http.StatusInternalServerError)
@@ -190,7 +365,7 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 	resp, err := adaptor.DoRequest(c, meta, requestBody)
 	if err != nil {
 		logger.Errorf(ctx, "DoRequest failed: %s", err.Error())
-		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		return wrapDoRequestError(err)
 	}
 
 	defer func(ctx context.Context) {
@@ -211,12 +386,17 @@ func RelayImageHelper(c *gin.Context, relayMode int) *relaymodel.ErrorWithStatus
 		if quota != 0 {
 			tokenName := c.GetString(ctxkey.TokenName)
 			logContent := fmt.Sprintf("倍率：%.2f × %.2f", modelRatio, groupRatio)
+			requestedModel := ""
+			if meta.IsFallback {
+				requestedModel = meta.OriginModelName
+			}
 			model.RecordConsumeLog(ctx, &model.Log{
 				UserId:           meta.UserId,
 				ChannelId:        meta.ChannelId,
 				PromptTokens:     0,
 				CompletionTokens: 0,
 				ModelName:        imageRequest.Model,
+				RequestedModel:   requestedModel,
 				TokenName:        tokenName,
 				Quota:            int(quota),
 				Content:          logContent,
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newImageEditContext(t *testing.T, fields map[string]string) *gin.Context {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		require.NoError(t, writer.WriteField(key, value))
+	}
+	part, err := writer.CreateFormFile("image", "image.png")
+	require.NoError(t, err)
+	_, _ = part.Write([]byte("\x89PNG image"))
+	part, err = writer.CreateFormFile("mask", "mask.png")
+	require.NoError(t, err)
+	_, _ = part.Write([]byte("\x89PNG mask"))
+	require.NoError(t, writer.Close())
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/images/edits", body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+func TestGetImageEditRequest(t *testing.T) {
+	c := newImageEditContext(t, map[string]string{"prompt": "a cat", "n": "2", "quality": "high"})
+	imageEditRequest, err := getImageEditRequest(c)
+	require.NoError(t, err)
+	defer imageEditRequest.Form.RemoveAll()
+
+	assert.Equal(t, "dall-e-2", imageEditRequest.Model)
+	assert.Equal(t, "a cat", imageEditRequest.Prompt)
+	assert.Equal(t, 2, imageEditRequest.N)
+	assert.Equal(t, "1024x1024", imageEditRequest.Size)
+	assert.Equal(t, "high", imageEditRequest.Quality)
+	require.Len(t, imageEditRequest.Image, 1)
+	require.NotNil(t, imageEditRequest.Mask)
+	// the body is left for the retries
+	requestBody, err := io.ReadAll(c.Request.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, requestBody)
+
+	_, err = getImageEditRequest(newImageEditContext(t, map[string]string{"n": "two"}))
+	assert.Error(t, err)
+}
+
+func TestRewriteImageEditForm(t *testing.T) {
+	c := newImageEditContext(t, map[string]string{"model": "dall-e-2", "prompt": "a cat"})
+	imageEditRequest, err := getImageEditRequest(c)
+	require.NoError(t, err)
+	defer imageEditRequest.Form.RemoveAll()
+	boundary, err := getMultipartBoundary(c.Request.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	body, err := rewriteImageEditForm(imageEditRequest.Form, boundary, "gpt-image-1")
+	require.NoError(t, err)
+	form, err := multipart.NewReader(body, boundary).ReadForm(imageEditFormMaxMemory)
+	require.NoError(t, err)
+	defer form.RemoveAll()
+	assert.Equal(t, []string{"gpt-image-1"}, form.Value["model"])
+	assert.Equal(t, []string{"a cat"}, form.Value["prompt"])
+	require.Len(t, form.File["mask"], 1)
+	mask, err := readImageEditFile(form.File["mask"][0])
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG mask", string(mask))
+}
+
+func readImageEditFile(fileHeader *multipart.FileHeader) ([]byte, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// ingressEncoder re-encodes the OpenAI chat completions output of the adaptors
+// into the API shape that the client originally used (Responses, Anthropic, ...).
+type ingressEncoder interface {
+	EncodeStreamChunk(chunk *openai.ChatCompletionsStreamResponse) []render.Event
+	EncodeStreamEnd(usage *model.Usage) []render.Event
+	EncodeResponse(response *openai.TextResponse) any
+}
+
+// ingressWriter sits in front of the client connection while an adaptor writes
+// its chat completions output, and hands that output to an ingressEncoder instead.
+type ingressWriter struct {
+	gin.ResponseWriter
+	encoder  ingressEncoder
+	isStream bool
+	header   http.Header
+	status   int
+	buffer   bytes.Buffer
+	started  bool
+	// encodeResponse converts a buffered non-stream response body
+	encodeResponse func(body []byte, usage *model.Usage) (any, error)
+}
+
+func newIngressWriter(w gin.ResponseWriter, encoder ingressEncoder, isStream bool) *ingressWriter {
+	return &ingressWriter{
+		ResponseWriter: w,
+		encoder:        encoder,
+		isStream:       isStream,
+		header:         make(http.Header),
+		status:         http.StatusOK,
+		encodeResponse: func(body []byte, usage *model.Usage) (any, error) {
+			var textResponse openai.TextResponse
+			if err := json.Unmarshal(body, &textResponse); err != nil {
+				return nil, err
+			}
+			if usage != nil {
+				textResponse.Usage = *usage
+			}
+			return encoder.EncodeResponse(&textResponse), nil
+		},
+	}
+}
+
+// newIngressEmbeddingWriter creates a writer for a non-stream embeddings response.
+func newIngressEmbeddingWriter(w gin.ResponseWriter, encode func(*openai.EmbeddingResponse) any) *ingressWriter {
+	return &ingressWriter{
+		ResponseWriter: w,
+		header:         make(http.Header),
+		status:         http.StatusOK,
+		encodeResponse: func(body []byte, usage *model.Usage) (any, error) {
+			var embeddingResponse openai.EmbeddingResponse
+			if err := json.Unmarshal(body, &embeddingResponse); err != nil {
+				return nil, err
+			}
+			return encode(&embeddingResponse), nil
+		},
+	}
+}
+
+// Header returns a scratch header map, so that upstream headers such as
+// Content-Length set by the adaptors never reach the client.
+func (w *ingressWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *ingressWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *ingressWriter) WriteHeaderNow() {}
+
+func (w *ingressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *ingressWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	if w.isStream {
+		w.consumeLines(false)
+	}
+	return len(data), nil
+}
+
+func (w *ingressWriter) Flush() {}
+
+func (w *ingressWriter) consumeLines(atEOF bool) {
+	for {
+		line, err := w.buffer.ReadString('\n')
+		if err != nil {
+			if !atEOF {
+				// keep the incomplete line for the next write
+				rest := line
+				w.buffer.Reset()
+				w.buffer.WriteString(rest)
+				return
+			}
+		}
+		w.handleLine(strings.TrimSpace(line))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (w *ingressWriter) handleLine(line string) {
+	if !strings.HasPrefix(line, "data:") {
+		return
+	}
+	data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+	if data == "" || strings.HasPrefix(data, "[DONE]") {
+		return
+	}
+	var chunk openai.ChatCompletionsStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		logger.SysError("error unmarshalling stream response: " + err.Error())
+		return
+	}
+	w.writeEvents(w.encoder.EncodeStreamChunk(&chunk))
+}
+
+func (w *ingressWriter) writeEvents(events []render.Event) {
+	if len(events) == 0 {
+		return
+	}
+	if !w.started {
+		w.started = true
+		header := w.ResponseWriter.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			logger.SysError("error marshalling stream event: " + err.Error())
+			continue
+		}
+		if event.Event != "" {
+			_, _ = fmt.Fprintf(w.ResponseWriter, "event: %s\n", event.Event)
+		}
+		_, _ = fmt.Fprintf(w.ResponseWriter, "data: %s\n\n", data)
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish emits the trailing stream events, or the whole converted response in non-stream mode.
+func (w *ingressWriter) finish(usage *model.Usage) {
+	if w.isStream {
+		w.consumeLines(true)
+		w.writeEvents(w.encoder.EncodeStreamEnd(usage))
+		return
+	}
+	response, err := w.encodeResponse(w.buffer.Bytes(), usage)
+	if err != nil {
+		// not the expected OpenAI response, pass it through untouched
+		w.ResponseWriter.Header().Set("Content-Type", w.header.Get("Content-Type"))
+		w.ResponseWriter.WriteHeader(w.status)
+		_, _ = w.ResponseWriter.Write(w.buffer.Bytes())
+		return
+	}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		logger.SysError("error marshalling response: " + err.Error())
+		return
+	}
+	w.ResponseWriter.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(jsonResponse)
+}
+
+// relayIngressRequest relays a request that arrived in another API shape and has been
+// converted to chat completions, re-encoding the adaptor's output with the given encoder.
+func relayIngressRequest(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest, encoder ingressEncoder) *model.ErrorWithStatusCode {
+	if textRequest.Stream {
+		// the encoders need the real usage for their final events
+		textRequest.StreamOptions = &model.StreamOptions{IncludeUsage: true}
+	}
+	// beneath the ingress writer, which only passes on the events it understands
+	stopHeartbeat := startStreamHeartbeat(c, textRequest.Stream)
+	defer stopHeartbeat()
+	writer := newIngressWriter(c.Writer, encoder, textRequest.Stream)
+	return relayIngress(c, meta, textRequest, relaymode.ChatCompletions, "/v1/chat/completions", writer)
+}
+
+// relayIngressEmbeddingRequest is relayIngressRequest for requests converted to embeddings.
+func relayIngressEmbeddingRequest(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest, encode func(*openai.EmbeddingResponse) any) *model.ErrorWithStatusCode {
+	writer := newIngressEmbeddingWriter(c.Writer, encode)
+	return relayIngress(c, meta, textRequest, relaymode.Embeddings, "/v1/embeddings", writer)
+}
+
+func relayIngress(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest, relayMode int, requestURLPath string, writer *ingressWriter) *model.ErrorWithStatusCode {
+	jsonData, err := json.Marshal(textRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "marshal_text_request_failed", http.StatusInternalServerError)
+	}
+	// the adaptors only see an OpenAI request
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+	meta.Mode = relayMode
+	meta.RequestURLPath = requestURLPath
+
+	c.Writer = writer
+	defer func() {
+		c.Writer = writer.ResponseWriter
+	}()
+	usage, bizErr := relayTextRequest(c, meta, textRequest)
+	if bizErr != nil {
+		return bizErr
+	}
+	writer.finish(usage)
+	return nil
+}
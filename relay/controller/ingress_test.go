@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LeXwDeX/one-api/common/render"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestIngressWriterStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := newIngressWriter(c.Writer, openai.NewResponsesStreamEncoder("gpt-4o"), true)
+	c.Writer = writer
+
+	c.Writer.Header().Set("Content-Length", "42")
+	render.StringData(c, `{"choices":[{"delta":{"content":"Hi"}}]}`)
+	// a chunk split across two writes
+	_, _ = c.Writer.Write([]byte(`data: {"choices":[{"delta":{"con`))
+	_, _ = c.Writer.Write([]byte("tent\":\" there\"}}]}\n\n"))
+	render.Done(c)
+	writer.finish(&model.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3})
+
+	body := recorder.Body.String()
+	assert.Empty(t, recorder.Header().Get("Content-Length"))
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, 2, strings.Count(body, "event: response.output_text.delta\n"))
+	assert.Contains(t, body, `"text":"Hi there"`)
+	assert.Contains(t, body, "event: response.completed\n")
+	assert.NotContains(t, body, "[DONE]")
+}
+
+func TestIngressWriterNonStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := newIngressWriter(c.Writer, openai.NewResponsesStreamEncoder("gpt-4o"), false)
+	c.Writer = writer
+
+	c.JSON(200, gin.H{
+		"model":   "gpt-4o",
+		"choices": []gin.H{{"message": gin.H{"role": "assistant", "content": "Hello"}, "finish_reason": "length"}},
+	})
+	writer.finish(&model.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3})
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"object":"response"`)
+	assert.Contains(t, body, `"status":"incomplete"`)
+	assert.Contains(t, body, `"text":"Hello"`)
+	assert.Contains(t, body, `"total_tokens":3`)
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/relay/adaptor/anthropic"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/controller/validator"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// https://docs.anthropic.com/en/api/messages
+
+func getAndValidateMessagesRequest(c *gin.Context) (*anthropic.IngressRequest, error) {
+	messagesRequest := &anthropic.IngressRequest{}
+	err := common.UnmarshalBodyReusable(c, messagesRequest)
+	if err != nil {
+		return nil, err
+	}
+	if messagesRequest.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if len(messagesRequest.Messages) == 0 {
+		return nil, errors.New("field messages is required")
+	}
+	if messagesRequest.MaxTokens <= 0 {
+		return nil, errors.New("field max_tokens is required")
+	}
+	return messagesRequest, nil
+}
+
+// RelayMessagesHelper serves an Anthropic Messages API request through the chat completions of any adaptor.
+func RelayMessagesHelper(c *gin.Context) *model.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	messagesRequest, err := getAndValidateMessagesRequest(c)
+	if err != nil {
+		logger.Errorf(ctx, "getAndValidateMessagesRequest failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	textRequest, err := anthropic.ConvertIngressRequest(messagesRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	if err = validator.ValidateTextRequest(textRequest, relaymode.ChatCompletions); err != nil {
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	return relayIngressRequest(c, meta, textRequest, anthropic.NewIngressStreamEncoder(meta, textRequest.Model))
+}
@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/LeXwDeX/one-api/common/client"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
+)
+
+var realtimeUpgrader = websocket.Upgrader{
+	Subprotocols: []string{"realtime"},
+	// clients are authenticated by their token, from whatever origin, as on the HTTP routes
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// realtimeSession relays a Realtime WebSocket session and bills every response.done event
+// as it arrives, so that a long session can't run past the quota of the user.
+type realtimeSession struct {
+	ctx      context.Context
+	meta     *meta.Meta
+	client   *websocket.Conn
+	upstream *websocket.Conn
+
+	modelRatio           float64
+	groupRatio           float64
+	completionRatio      float64
+	cacheReadRatio       float64
+	audioPromptRatio     float64
+	audioCompletionRatio float64
+
+	promptTokens          int
+	completionTokens      int
+	cachedTokens          int
+	audioPromptTokens     int
+	audioCompletionTokens int
+	quota                 int64
+}
+
+func RelayRealtimeHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		return openai.ErrorWrapper(errors.New("realtime api requires a websocket connection"), "websocket_upgrade_required", http.StatusBadRequest)
+	}
+	meta.OriginModelName = c.GetString(ctxkey.RequestModel)
+	meta.ActualModelName, _ = getMappedModelName(meta.OriginModelName, meta.ModelMapping)
+	meta.IsStream = true
+
+	userQuota, err := model.CacheGetUserQuota(ctx, meta.UserId)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota <= 0 {
+		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+	}
+
+	fullRequestURL, err := openai.GetRealtimeRequestURL(meta)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_channel_type", http.StatusBadRequest)
+	}
+	startTime := time.Now()
+	upstream, resp, err := client.WebSocketDialer.DialContext(ctx, fullRequestURL, openai.GetRealtimeRequestHeader(c, meta))
+	if err != nil {
+		if resp != nil {
+			return RelayErrorHandler(resp)
+		}
+		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	c.Set(ctxkey.UpstreamFirstByteLatency, time.Since(startTime))
+
+	conn, err := realtimeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has answered the client already
+		logger.Errorf(ctx, "upgrade realtime connection failed: %s", err.Error())
+		_ = upstream.Close()
+		return nil
+	}
+	session := &realtimeSession{
+		ctx:                  ctx,
+		meta:                 meta,
+		client:               conn,
+		upstream:             upstream,
+		modelRatio:           billingratio.GetModelRatio(meta.ActualModelName, meta.ChannelType),
+		groupRatio:           billingratio.GetGroupRatio(meta.Group),
+		completionRatio:      billingratio.GetCompletionRatio(meta.ActualModelName, meta.ChannelType),
+		cacheReadRatio:       billingratio.GetCacheReadRatio(meta.ActualModelName, meta.ChannelType),
+		audioPromptRatio:     billingratio.GetAudioPromptRatio(meta.ActualModelName, meta.ChannelType),
+		audioCompletionRatio: billingratio.GetAudioCompletionRatio(meta.ActualModelName, meta.ChannelType),
+	}
+	session.relay()
+	session.recordLog()
+	// the session is over, errors from here on can't reach the client anyway
+	return nil
+}
+
+func (s *realtimeSession) relay() {
+	done := make(chan struct{}, 2)
+	go func() {
+		relayWebSocket(s.client, s.upstream, nil)
+		done <- struct{}{}
+	}()
+	go func() {
+		relayWebSocket(s.upstream, s.client, s.handleServerEvent)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = s.client.Close()
+	_ = s.upstream.Close()
+	<-done
+}
+
+// relayWebSocket copies the messages of src to dst until either side ends or onMessage
+// returns false, passing a close frame of src on to dst.
+func relayWebSocket(src *websocket.Conn, dst *websocket.Conn, onMessage func(data []byte) bool) {
+	for {
+		messageType, data, err := src.ReadMessage()
+		if err != nil {
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && closeErr.Code != websocket.CloseNoStatusReceived && closeErr.Code != websocket.CloseAbnormalClosure {
+				closeMessage = websocket.FormatCloseMessage(closeErr.Code, closeErr.Text)
+			}
+			_ = dst.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+			return
+		}
+		if err = dst.WriteMessage(messageType, data); err != nil {
+			return
+		}
+		if onMessage != nil && messageType == websocket.TextMessage && !onMessage(data) {
+			return
+		}
+	}
+}
+
+// handleServerEvent bills response.done events and ends the session once the quota runs out.
+func (s *realtimeSession) handleServerEvent(data []byte) bool {
+	if !bytes.Contains(data, []byte(`"response.done"`)) {
+		return true
+	}
+	var event openai.RealtimeEvent
+	if err := json.Unmarshal(data, &event); err != nil || event.Type != "response.done" || event.Response == nil || event.Response.Usage == nil {
+		return true
+	}
+	s.consume(event.Response.Usage)
+	if !s.isQuotaExhausted() {
+		return true
+	}
+	logger.Infof(s.ctx, "user %d ran out of quota, closing the realtime session", s.meta.UserId)
+	errorEvent, _ := json.Marshal(openai.RealtimeEvent{
+		Type: "error",
+		Error: &relaymodel.Error{
+			Message: "user quota is not enough",
+			Type:    "insufficient_quota",
+			Code:    "insufficient_user_quota",
+		},
+	})
+	_ = s.client.WriteMessage(websocket.TextMessage, errorEvent)
+	deadline := time.Now().Add(time.Second)
+	_ = s.client.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "quota exhausted"), deadline)
+	_ = s.upstream.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	return false
+}
+
+func (s *realtimeSession) consume(usage *openai.RealtimeUsage) {
+	ratio := s.modelRatio * s.groupRatio
+	quota := getRealtimeQuota(usage, ratio, s.completionRatio, s.cacheReadRatio, s.audioPromptRatio, s.audioCompletionRatio)
+	err := model.PostConsumeTokenQuota(s.meta.TokenId, quota)
+	if err != nil {
+		logger.Error(s.ctx, "error consuming token remain quota: "+err.Error())
+	}
+	err = model.CacheUpdateUserQuota(s.ctx, s.meta.UserId)
+	if err != nil {
+		logger.Error(s.ctx, "error update user quota cache: "+err.Error())
+	}
+	ratelimit.RecordTokens(s.ctx, s.meta.RateLimitScopes, usage.TotalTokens)
+	s.promptTokens += usage.InputTokens
+	s.completionTokens += usage.OutputTokens
+	s.cachedTokens += usage.InputTokenDetails.CachedTokens
+	s.audioPromptTokens += usage.InputTokenDetails.AudioTokens
+	s.audioCompletionTokens += usage.OutputTokenDetails.AudioTokens
+	s.quota += quota
+}
+
+func (s *realtimeSession) isQuotaExhausted() bool {
+	userQuota, err := model.CacheGetUserQuota(s.ctx, s.meta.UserId)
+	if err == nil && userQuota <= 0 {
+		return true
+	}
+	token, err := model.GetTokenById(s.meta.TokenId)
+	return err == nil && !token.UnlimitedQuota && token.RemainQuota <= 0
+}
+
+// recordLog writes a single consume log for the whole session.
+func (s *realtimeSession) recordLog() {
+	if s.promptTokens+s.completionTokens == 0 {
+		return
+	}
+	logContent := fmt.Sprintf("倍率：%.2f × %.2f × %.2f", s.modelRatio, s.groupRatio, s.completionRatio)
+	if s.audioPromptTokens > 0 || s.audioCompletionTokens > 0 {
+		logContent += fmt.Sprintf("，音频输入 %d tokens × %.2f，音频输出 %d tokens × %.2f", s.audioPromptTokens, s.audioPromptRatio, s.audioCompletionTokens, s.audioCompletionRatio)
+	}
+	if s.cachedTokens > 0 {
+		logContent += fmt.Sprintf("，缓存读取 %d tokens × %.2f", s.cachedTokens, s.cacheReadRatio)
+	}
+	requestedModel := ""
+	if s.meta.IsFallback {
+		requestedModel = s.meta.OriginModelName
+	}
+	model.RecordConsumeLog(s.ctx, &model.Log{
+		UserId:           s.meta.UserId,
+		ChannelId:        s.meta.ChannelId,
+		PromptTokens:     s.promptTokens,
+		CompletionTokens: s.completionTokens,
+		CachedTokens:     s.cachedTokens,
+		ModelName:        s.meta.ActualModelName,
+		RequestedModel:   requestedModel,
+		TokenName:        s.meta.TokenName,
+		Quota:            int(s.quota),
+		Content:          logContent,
+		IsStream:         true,
+		ElapsedTime:      helper.CalcElapsedTime(s.meta.StartTime),
+	})
+	model.UpdateUserUsedQuotaAndRequestCount(s.meta.UserId, s.quota)
+	model.UpdateChannelUsedQuota(s.meta.ChannelId, s.quota)
+}
+
+// getRealtimeQuota bills the uncached audio tokens of a response at the audio ratios. Cached
+// tokens cost the same whether text or audio, so all of them go at the cache read ratio.
+func getRealtimeQuota(usage *openai.RealtimeUsage, ratio float64, completionRatio float64, cacheReadRatio float64, audioPromptRatio float64, audioCompletionRatio float64) int64 {
+	audioPromptTokens := usage.InputTokenDetails.AudioTokens - usage.InputTokenDetails.CachedTokensDetails.AudioTokens
+	if audioPromptTokens < 0 {
+		audioPromptTokens = 0
+	}
+	billedPromptTokens := getBilledPromptTokens(usage.InputTokens-audioPromptTokens, usage.InputTokenDetails.CachedTokens, 0, cacheReadRatio, 1) +
+		float64(audioPromptTokens)*audioPromptRatio
+	audioCompletionTokens := usage.OutputTokenDetails.AudioTokens
+	billedCompletionTokens := float64(usage.OutputTokens-audioCompletionTokens) + float64(audioCompletionTokens)*audioCompletionRatio
+	quota := int64(math.Ceil((billedPromptTokens + billedCompletionTokens*completionRatio) * ratio))
+	if ratio != 0 && quota <= 0 && usage.TotalTokens > 0 {
+		quota = 1
+	}
+	return quota
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+)
+
+func TestGetRealtimeQuota(t *testing.T) {
+	usage := &openai.RealtimeUsage{
+		TotalTokens:  1300,
+		InputTokens:  1000,
+		OutputTokens: 300,
+		InputTokenDetails: openai.RealtimeInputTokenDetails{
+			CachedTokens: 400,
+			TextTokens:   600,
+			AudioTokens:  400,
+			CachedTokensDetails: openai.RealtimeTokenDetails{
+				TextTokens:  300,
+				AudioTokens: 100,
+			},
+		},
+		OutputTokenDetails: openai.RealtimeTokenDetails{
+			TextTokens:  100,
+			AudioTokens: 200,
+		},
+	}
+	// 300 uncached text + 400 cached * 0.5 + 300 uncached audio * 8 = 2900 prompt,
+	// (100 text + 200 audio * 4) * 4 = 3600 completion
+	assert.Equal(t, int64(6500), getRealtimeQuota(usage, 1, 4, 0.5, 8, 4))
+	assert.Equal(t, int64(1), getRealtimeQuota(&openai.RealtimeUsage{TotalTokens: 1, InputTokens: 1}, 0.0001, 1, 1, 1, 1))
+	assert.Equal(t, int64(0), getRealtimeQuota(&openai.RealtimeUsage{}, 1, 1, 1, 1, 1))
+}
+
+func TestRelayWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	serverConns := make(chan *websocket.Conn, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		serverConns <- conn
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// src and dst are the server ends of two connections, the client ends play the peers
+	srcPeer, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer srcPeer.Close()
+	src := <-serverConns
+	dstPeer, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer dstPeer.Close()
+	dst := <-serverConns
+
+	var seen []string
+	done := make(chan struct{})
+	go func() {
+		relayWebSocket(src, dst, func(data []byte) bool {
+			seen = append(seen, string(data))
+			return true
+		})
+		close(done)
+	}()
+
+	require.NoError(t, srcPeer.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.done"}`)))
+	messageType, data, err := dstPeer.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, messageType)
+	assert.Equal(t, `{"type":"response.done"}`, string(data))
+
+	require.NoError(t, srcPeer.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "bye")))
+	_, _, err = dstPeer.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+	<-done
+	assert.Equal(t, []string{`{"type":"response.done"}`}, seen)
+}
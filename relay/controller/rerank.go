@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay"
+	"github.com/LeXwDeX/one-api/relay/adaptor"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
+)
+
+func getAndValidateRerankRequest(c *gin.Context) (*relaymodel.RerankRequest, error) {
+	rerankRequest := &relaymodel.RerankRequest{}
+	err := common.UnmarshalBodyReusable(c, rerankRequest)
+	if err != nil {
+		return nil, err
+	}
+	if rerankRequest.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if rerankRequest.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	if len(rerankRequest.Documents) == 0 {
+		return nil, errors.New("documents is required")
+	}
+	if rerankRequest.TopN < 0 {
+		return nil, errors.New("top_n must not be negative")
+	}
+	return rerankRequest, nil
+}
+
+// countRerankTokens estimates the tokens of a rerank, for upstreams that report no usage.
+func countRerankTokens(request *relaymodel.RerankRequest) int {
+	tokens := 0
+	for _, document := range request.Documents {
+		text, ok := document.(string)
+		if !ok {
+			jsonDocument, _ := json.Marshal(document)
+			text = string(jsonDocument)
+		}
+		// the query is ranked against every document
+		tokens += openai.CountTokenText(request.Query, request.Model) + openai.CountTokenText(text, request.Model)
+	}
+	return tokens
+}
+
+// getRerankQuota bills per search unit, priced like an image, when the upstream counts them
+// as Cohere does, and per token otherwise.
+func getRerankQuota(searchUnits int, tokens int, ratio float64) int64 {
+	if searchUnits > 0 {
+		return int64(math.Ceil(float64(searchUnits) * ratio * 1000))
+	}
+	quota := int64(math.Ceil(float64(tokens) * ratio))
+	if ratio != 0 && quota <= 0 && tokens > 0 {
+		quota = 1
+	}
+	return quota
+}
+
+func RelayRerankHelper(c *gin.Context) *relaymodel.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	rerankRequest, err := getAndValidateRerankRequest(c)
+	if err != nil {
+		logger.Errorf(ctx, "getAndValidateRerankRequest failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_rerank_request", http.StatusBadRequest)
+	}
+
+	// map model name
+	meta.OriginModelName = rerankRequest.Model
+	rerankRequest.Model, _ = getMappedModelName(rerankRequest.Model, meta.ModelMapping)
+	meta.ActualModelName = rerankRequest.Model
+
+	a := relay.GetAdaptor(meta.APIType)
+	if a == nil {
+		return openai.ErrorWrapper(fmt.Errorf("invalid api type: %d", meta.APIType), "invalid_api_type", http.StatusBadRequest)
+	}
+	reranker, ok := a.(adaptor.Reranker)
+	if !ok {
+		return openai.ErrorWrapper(fmt.Errorf("rerank is not supported by channel type %d", meta.ChannelType), "rerank_not_supported", http.StatusBadRequest)
+	}
+	a.Init(meta)
+
+	modelRatio := billingratio.GetModelRatio(rerankRequest.Model, meta.ChannelType)
+	groupRatio := billingratio.GetGroupRatio(meta.Group)
+	ratio := modelRatio * groupRatio
+	userQuota, err := model.CacheGetUserQuota(ctx, meta.UserId)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota <= 0 {
+		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+	}
+
+	convertedRequest, err := reranker.ConvertRerankRequest(rerankRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "convert_request_failed", http.StatusInternalServerError)
+	}
+	jsonData, err := json.Marshal(convertedRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "marshal_request_failed", http.StatusInternalServerError)
+	}
+	resp, err := a.DoRequest(c, meta, bytes.NewBuffer(jsonData))
+	if err != nil {
+		logger.Errorf(ctx, "DoRequest failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	if isErrorHappened(meta, resp) {
+		return RelayErrorHandler(resp)
+	}
+	rerankResponse, respErr := reranker.DoRerankResponse(c, resp, meta)
+	if respErr != nil {
+		logger.Errorf(ctx, "respErr is not nil: %+v", respErr)
+		return respErr
+	}
+
+	searchUnits := rerankResponse.GetSearchUnits()
+	tokens := rerankResponse.GetTokens()
+	if searchUnits == 0 && tokens == 0 {
+		tokens = countRerankTokens(rerankRequest)
+	}
+	quota := getRerankQuota(searchUnits, tokens, ratio)
+	ratelimit.RecordTokens(ctx, meta.RateLimitScopes, tokens)
+	go func() {
+		err := model.PostConsumeTokenQuota(meta.TokenId, quota)
+		if err != nil {
+			logger.Error(ctx, "error consuming token remain quota: "+err.Error())
+		}
+		err = model.CacheUpdateUserQuota(ctx, meta.UserId)
+		if err != nil {
+			logger.Error(ctx, "error update user quota cache: "+err.Error())
+		}
+		logContent := fmt.Sprintf("倍率：%.2f × %.2f", modelRatio, groupRatio)
+		if searchUnits > 0 {
+			logContent += fmt.Sprintf("，搜索单元 %d", searchUnits)
+		}
+		requestedModel := ""
+		if meta.IsFallback {
+			requestedModel = meta.OriginModelName
+		}
+		model.RecordConsumeLog(ctx, &model.Log{
+			UserId:         meta.UserId,
+			ChannelId:      meta.ChannelId,
+			PromptTokens:   tokens,
+			ModelName:      meta.ActualModelName,
+			RequestedModel: requestedModel,
+			TokenName:      meta.TokenName,
+			Quota:          int(quota),
+			Content:        logContent,
+			ElapsedTime:    helper.CalcElapsedTime(meta.StartTime),
+		})
+		model.UpdateUserUsedQuotaAndRequestCount(meta.UserId, quota)
+		model.UpdateChannelUsedQuota(meta.ChannelId, quota)
+	}()
+	return nil
+}
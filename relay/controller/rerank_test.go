@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestGetRerankQuota(t *testing.T) {
+	// Cohere bills a search unit, priced like an image
+	assert.Equal(t, int64(1000), getRerankQuota(1, 500, 1))
+	assert.Equal(t, int64(500), getRerankQuota(0, 500, 1))
+	assert.Equal(t, int64(1), getRerankQuota(0, 1, 0.01))
+	assert.Equal(t, int64(0), getRerankQuota(0, 0, 1))
+}
+
+func TestRerankResponseUsage(t *testing.T) {
+	for _, tc := range []struct {
+		body        string
+		searchUnits int
+		tokens      int
+	}{
+		{`{"results":[],"usage":{"total_tokens":42}}`, 0, 42},
+		{`{"results":[],"meta":{"billed_units":{"search_units":1}}}`, 1, 0},
+		{`{"results":[],"meta":{"billed_units":{"search_units":0,"input_tokens":7},"tokens":{"input_tokens":9,"output_tokens":0}}}`, 0, 9},
+	} {
+		var rerankResponse relaymodel.RerankResponse
+		require.NoError(t, json.Unmarshal([]byte(tc.body), &rerankResponse))
+		assert.Equal(t, tc.searchUnits, rerankResponse.GetSearchUnits(), tc.body)
+		assert.Equal(t, tc.tokens, rerankResponse.GetTokens(), tc.body)
+	}
+}
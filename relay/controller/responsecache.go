@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/responsecache"
+)
+
+// getResponseCacheKey returns the key the response to the request is cached under, or "" when
+// it is not to be cached. noCache tells to skip the lookup but still refresh the entry.
+func getResponseCacheKey(c *gin.Context, meta *meta.Meta, textRequest *relaymodel.GeneralOpenAIRequest) (key string, noCache bool) {
+	if !meta.ResponseCacheEnabled || !responsecache.IsCacheable(meta.Mode, textRequest) {
+		return "", false
+	}
+	noCache, noStore := responsecache.IsBypassed(c.Request.Header)
+	if noStore {
+		return "", false
+	}
+	key, err := responsecache.GetKey(meta.UserId, meta.Mode, textRequest)
+	if err != nil {
+		logger.Warnf(c.Request.Context(), "get response cache key failed: %s", err.Error())
+		return "", false
+	}
+	return key, noCache
+}
+
+// replayResponseCache writes a cached response to the client as it was first written.
+func replayResponseCache(c *gin.Context, meta *meta.Meta, entry *responsecache.Entry) *relaymodel.ErrorWithStatusCode {
+	userQuota, err := model.CacheGetUserQuota(c.Request.Context(), meta.UserId)
+	if err != nil {
+		return openai.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+	if userQuota <= 0 {
+		return openai.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusForbidden)
+	}
+	meta.IsResponseCacheHit = true
+	c.Set(ctxkey.ResponseCacheHit, true)
+	c.Writer.Header().Set("Content-Type", entry.ContentType)
+	c.Writer.Header().Set("X-Response-Cache", "hit")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(entry.Body)
+	return nil
+}
+
+func storeResponseCache(ctx context.Context, key string, recorder *responsecache.Recorder, usage *relaymodel.Usage) {
+	if usage == nil || usage.TotalTokens == 0 {
+		return
+	}
+	entry, ok := recorder.Entry()
+	if !ok {
+		return
+	}
+	entry.Usage = usage
+	if err := responsecache.Set(ctx, key, entry); err != nil {
+		logger.Warnf(ctx, "store response cache failed: %s", err.Error())
+	}
+}
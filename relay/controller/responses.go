@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/helper"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/relay"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	"github.com/LeXwDeX/one-api/relay/billing"
+	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
+	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/controller/validator"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/paramoverride"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// https://platform.openai.com/docs/api-reference/responses
+
+func getAndValidateResponsesRequest(c *gin.Context) (*model.ResponsesRequest, error) {
+	responsesRequest := &model.ResponsesRequest{}
+	err := common.UnmarshalBodyReusable(c, responsesRequest)
+	if err != nil {
+		return nil, err
+	}
+	if responsesRequest.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if responsesRequest.Input == nil {
+		return nil, errors.New("field input is required")
+	}
+	return responsesRequest, nil
+}
+
+// supportsNativeResponses reports whether the channel serves /v1/responses itself.
+func supportsNativeResponses(channelType int) bool {
+	switch channelType {
+	case channeltype.OpenAI, channeltype.Azure:
+		return true
+	}
+	return false
+}
+
+func RelayResponsesHelper(c *gin.Context) *model.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	responsesRequest, err := getAndValidateResponsesRequest(c)
+	if err != nil {
+		logger.Errorf(ctx, "getAndValidateResponsesRequest failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_responses_request", http.StatusBadRequest)
+	}
+	if !supportsNativeResponses(meta.ChannelType) {
+		return relayResponsesAsChat(c, meta, responsesRequest)
+	}
+	meta.IsStream = responsesRequest.Stream
+	stopHeartbeat := startStreamHeartbeat(c, meta.IsStream)
+	defer stopHeartbeat()
+
+	// map model name
+	meta.OriginModelName = responsesRequest.Model
+	responsesRequest.Model, _ = getMappedModelName(responsesRequest.Model, meta.ModelMapping)
+	meta.ActualModelName = responsesRequest.Model
+	systemPromptReset := meta.ForcedSystemPrompt != ""
+	if systemPromptReset {
+		responsesRequest.Instructions = meta.ForcedSystemPrompt
+	}
+	// the chat completions view of the request is only used for counting & billing
+	textRequest, err := openai.ConvertResponsesRequest(responsesRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_responses_request", http.StatusBadRequest)
+	}
+	modelRatio := billingratio.GetModelRatio(textRequest.Model, meta.ChannelType)
+	groupRatio := billingratio.GetGroupRatio(meta.Group)
+	ratio := modelRatio * groupRatio
+	if meta.IsBatch {
+		ratio *= config.BatchRatio
+	}
+	promptTokens := getPromptTokens(textRequest, relaymode.ChatCompletions)
+	meta.PromptTokens = promptTokens
+	preConsumedQuota, bizErr := preConsumeQuota(ctx, textRequest, promptTokens, ratio, meta)
+	if bizErr != nil {
+		logger.Warnf(ctx, "preConsumeQuota failed: %+v", *bizErr)
+		return bizErr
+	}
+
+	adaptor := relay.GetAdaptor(meta.APIType)
+	if adaptor == nil {
+		return openai.ErrorWrapper(fmt.Errorf("invalid api type: %d", meta.APIType), "invalid_api_type", http.StatusBadRequest)
+	}
+	adaptor.Init(meta)
+
+	requestBody, err := getResponsesRequestBody(c, meta, responsesRequest)
+	if err != nil {
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return openai.ErrorWrapper(err, "convert_request_failed", http.StatusInternalServerError)
+	}
+	resp, err := adaptor.DoRequest(c, meta, requestBody)
+	if err != nil {
+		logger.Errorf(ctx, "DoRequest failed: %s", err.Error())
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return wrapDoRequestError(err)
+	}
+	if isErrorHappened(meta, resp) {
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return RelayErrorHandler(resp)
+	}
+
+	usage, respErr := adaptor.DoResponse(c, resp, meta)
+	if respErr != nil {
+		logger.Errorf(ctx, "respErr is not nil: %+v", respErr)
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return respErr
+	}
+	if ctx.Err() != nil {
+		// the client went away mid-response, what was generated until then is billed all the same
+		meta.IsClientAborted = true
+		logger.Warnf(ctx, "client aborted the request, billing the partial response")
+		ctx = helper.SetRequestID(context.Background(), helper.GetRequestID(ctx))
+	}
+	go postConsumeQuota(ctx, usage, meta, textRequest, ratio, preConsumedQuota, modelRatio, groupRatio, systemPromptReset)
+	return nil
+}
+
+// getResponsesRequestBody forwards the raw request body, only rewriting the fields One API overrides,
+// so that Responses features unknown to One API still reach the upstream.
+func getResponsesRequestBody(c *gin.Context, meta *meta.Meta, responsesRequest *model.ResponsesRequest) (io.Reader, error) {
+	if meta.OriginModelName == meta.ActualModelName && meta.ForcedSystemPrompt == "" &&
+		!paramoverride.Matches(meta.Config.ParamOverrides, meta.ActualModelName) {
+		return c.Request.Body, nil
+	}
+	requestBody, err := common.GetRequestBody(c)
+	if err != nil {
+		return nil, err
+	}
+	rawRequest := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(requestBody))
+	decoder.UseNumber()
+	if err = decoder.Decode(&rawRequest); err != nil {
+		return nil, err
+	}
+	rawRequest["model"] = responsesRequest.Model
+	if meta.ForcedSystemPrompt != "" {
+		rawRequest["instructions"] = responsesRequest.Instructions
+	}
+	jsonData, err := json.Marshal(rawRequest)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err = paramoverride.Apply(meta.Config.ParamOverrides, meta.ActualModelName, jsonData)
+	if err != nil {
+		logger.Debugf(c.Request.Context(), "override request failed: %s\n", err.Error())
+		return nil, err
+	}
+	return bytes.NewBuffer(jsonData), nil
+}
+
+// relayResponsesAsChat serves a Responses API request through the chat completions of any adaptor.
+func relayResponsesAsChat(c *gin.Context, meta *meta.Meta, responsesRequest *model.ResponsesRequest) *model.ErrorWithStatusCode {
+	if responsesRequest.PreviousResponseId != "" {
+		return openai.ErrorWrapper(errors.New("previous_response_id is not supported by this channel"), "invalid_responses_request", http.StatusBadRequest)
+	}
+	textRequest, err := openai.ConvertResponsesRequest(responsesRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_responses_request", http.StatusBadRequest)
+	}
+	if err = validator.ValidateTextRequest(textRequest, relaymode.ChatCompletions); err != nil {
+		return openai.ErrorWrapper(err, "invalid_responses_request", http.StatusBadRequest)
+	}
+	return relayIngressRequest(c, meta, textRequest, openai.NewResponsesStreamEncoder(textRequest.Model))
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/model"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func TestGetResponsesRequestBodyParamOverrides(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	body := `{"model":"o3","input":"hi","temperature":0.2,"service_tier":"flex"}`
+	c.Request = httptest.NewRequest("POST", "/v1/responses", strings.NewReader(body))
+	m := &meta.Meta{
+		OriginModelName: "o3",
+		ActualModelName: "o3",
+		Config: model.ChannelConfig{ParamOverrides: []model.ParamOverride{{
+			Delete: []string{"service_tier"},
+			Force:  map[string]any{"temperature": 1},
+		}}},
+	}
+	requestBody, err := getResponsesRequestBody(c, m, &relaymodel.ResponsesRequest{Model: "o3"})
+	require.NoError(t, err)
+	jsonData, err := io.ReadAll(requestBody)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"o3","input":"hi","temperature":1}`, string(jsonData))
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/helper"
 	"github.com/LeXwDeX/one-api/common/logger"
 	"github.com/LeXwDeX/one-api/relay"
 	"github.com/LeXwDeX/one-api/relay/adaptor"
@@ -20,6 +22,8 @@ import (
 	"github.com/LeXwDeX/one-api/relay/channeltype"
 	"github.com/LeXwDeX/one-api/relay/meta"
 	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/paramoverride"
+	"github.com/LeXwDeX/one-api/relay/responsecache"
 )
 
 func RelayTextHelper(c *gin.Context) *model.ErrorWithStatusCode {
@@ -31,6 +35,16 @@ func RelayTextHelper(c *gin.Context) *model.ErrorWithStatusCode {
 		logger.Errorf(ctx, "getAndValidateTextRequest failed: %s", err.Error())
 		return openai.ErrorWrapper(err, "invalid_text_request", http.StatusBadRequest)
 	}
+	stopHeartbeat := startStreamHeartbeat(c, textRequest.Stream)
+	defer stopHeartbeat()
+	_, bizErr := relayTextRequest(c, meta, textRequest)
+	return bizErr
+}
+
+// relayTextRequest relays a parsed and validated text request through the channel's adaptor,
+// and returns the usage it was billed for.
+func relayTextRequest(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest) (*model.Usage, *model.ErrorWithStatusCode) {
+	ctx := c.Request.Context()
 	meta.IsStream = textRequest.Stream
 
 	// map model name
@@ -39,52 +53,90 @@ func RelayTextHelper(c *gin.Context) *model.ErrorWithStatusCode {
 	meta.ActualModelName = textRequest.Model
 	// set system prompt if not empty
 	systemPromptReset := setSystemPrompt(ctx, textRequest, meta.ForcedSystemPrompt)
+	// deterministic requests may be answered from the response cache
+	cacheKey, noCache := getResponseCacheKey(c, meta, textRequest)
+	// swap references to uploaded files for what the channel understands
+	if bizErr := resolveFileReferences(ctx, meta, textRequest); bizErr != nil {
+		return nil, bizErr
+	}
 	// get model ratio & group ratio
 	modelRatio := billingratio.GetModelRatio(textRequest.Model, meta.ChannelType)
 	groupRatio := billingratio.GetGroupRatio(meta.Group)
 	ratio := modelRatio * groupRatio
+	if meta.IsBatch {
+		ratio *= config.BatchRatio
+	}
+	if cacheKey != "" && !noCache {
+		if entry, ok := responsecache.Get(ctx, cacheKey); ok {
+			if bizErr := replayResponseCache(c, meta, entry); bizErr != nil {
+				return nil, bizErr
+			}
+			go postConsumeQuota(ctx, entry.Usage, meta, textRequest, ratio*config.ResponseCacheHitRatio, 0, modelRatio, groupRatio, systemPromptReset)
+			return entry.Usage, nil
+		}
+	}
 	// pre-consume quota
 	promptTokens := getPromptTokens(textRequest, meta.Mode)
 	meta.PromptTokens = promptTokens
 	preConsumedQuota, bizErr := preConsumeQuota(ctx, textRequest, promptTokens, ratio, meta)
 	if bizErr != nil {
 		logger.Warnf(ctx, "preConsumeQuota failed: %+v", *bizErr)
-		return bizErr
+		return nil, bizErr
 	}
 
 	adaptor := relay.GetAdaptor(meta.APIType)
 	if adaptor == nil {
-		return openai.ErrorWrapper(fmt.Errorf("invalid api type: %d", meta.APIType), "invalid_api_type", http.StatusBadRequest)
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return nil, openai.ErrorWrapper(fmt.Errorf("invalid api type: %d", meta.APIType), "invalid_api_type", http.StatusBadRequest)
 	}
 	adaptor.Init(meta)
 
 	// get request body
 	requestBody, err := getRequestBody(c, meta, textRequest, adaptor)
 	if err != nil {
-		return openai.ErrorWrapper(err, "convert_request_failed", http.StatusInternalServerError)
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return nil, openai.ErrorWrapper(err, "convert_request_failed", http.StatusInternalServerError)
 	}
 
 	// do request
 	resp, err := adaptor.DoRequest(c, meta, requestBody)
 	if err != nil {
 		logger.Errorf(ctx, "DoRequest failed: %s", err.Error())
-		return openai.ErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+		// timed out attempts are retried, each pre-consuming again
+		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
+		return nil, wrapDoRequestError(err)
 	}
 	if isErrorHappened(meta, resp) {
 		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
-		return RelayErrorHandler(resp)
+		return nil, RelayErrorHandler(resp)
 	}
 
 	// do response
+	var recorder *responsecache.Recorder
+	if cacheKey != "" {
+		recorder = responsecache.NewRecorder(c.Writer)
+		c.Writer = recorder
+		defer func() {
+			c.Writer = recorder.ResponseWriter
+		}()
+	}
 	usage, respErr := adaptor.DoResponse(c, resp, meta)
 	if respErr != nil {
 		logger.Errorf(ctx, "respErr is not nil: %+v", respErr)
 		billing.ReturnPreConsumedQuota(ctx, preConsumedQuota, meta.TokenId)
-		return respErr
+		return nil, respErr
+	}
+	if ctx.Err() != nil {
+		// the client went away mid-response, what was generated until then is billed all the same
+		meta.IsClientAborted = true
+		logger.Warnf(ctx, "client aborted the request, billing the partial response")
+		ctx = helper.SetRequestID(context.Background(), helper.GetRequestID(ctx))
+	} else if recorder != nil {
+		storeResponseCache(ctx, cacheKey, recorder, usage)
 	}
 	// post-consume quota
 	go postConsumeQuota(ctx, usage, meta, textRequest, ratio, preConsumedQuota, modelRatio, groupRatio, systemPromptReset)
-	return nil
+	return usage, nil
 }
 
 func getRequestBody(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest, adaptor adaptor.Adaptor) (io.Reader, error) {
@@ -92,9 +144,22 @@ func getRequestBody(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralO
 		meta.APIType == apitype.OpenAI &&
 		meta.OriginModelName == meta.ActualModelName &&
 		meta.ChannelType != channeltype.Baichuan &&
-		meta.ForcedSystemPrompt == "" {
+		meta.ForcedSystemPrompt == "" &&
+		!hasFileParts(textRequest) {
 		// no need to convert request for openai
-		return c.Request.Body, nil
+		if !paramoverride.Matches(meta.Config.ParamOverrides, meta.ActualModelName) {
+			return c.Request.Body, nil
+		}
+		requestBody, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		jsonData, err := paramoverride.Apply(meta.Config.ParamOverrides, meta.ActualModelName, requestBody)
+		if err != nil {
+			logger.Debugf(c.Request.Context(), "override request failed: %s\n", err.Error())
+			return nil, err
+		}
+		return bytes.NewBuffer(jsonData), nil
 	}
 
 	// get request body
@@ -109,6 +174,11 @@ func getRequestBody(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralO
 		logger.Debugf(c.Request.Context(), "converted request json_marshal_failed: %s\n", err.Error())
 		return nil, err
 	}
+	jsonData, err = paramoverride.Apply(meta.Config.ParamOverrides, meta.ActualModelName, jsonData)
+	if err != nil {
+		logger.Debugf(c.Request.Context(), "override request failed: %s\n", err.Error())
+		return nil, err
+	}
 	logger.Debugf(c.Request.Context(), "converted request: \n%s", string(jsonData))
 	requestBody = bytes.NewBuffer(jsonData)
 	return requestBody, nil
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/logger"
+	"github.com/LeXwDeX/one-api/relay/adaptor/anthropic"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	billingratio "github.com/LeXwDeX/one-api/relay/billing/ratio"
+	"github.com/LeXwDeX/one-api/relay/controller/validator"
+	"github.com/LeXwDeX/one-api/relay/meta"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// TokenizeResponse tells how a request is counted and what relaying it would pre-consume.
+type TokenizeResponse struct {
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	PreConsumedQuota int64   `json:"pre_consumed_quota"`
+	ModelRatio       float64 `json:"model_ratio"`
+	GroupRatio       float64 `json:"group_ratio"`
+	Ratio            float64 `json:"ratio"`
+}
+
+// getTokenizeRelayMode tells which kind of request the body is, by the field it carries.
+func getTokenizeRelayMode(textRequest *model.GeneralOpenAIRequest) (int, error) {
+	switch {
+	case len(textRequest.Messages) != 0:
+		return relaymode.ChatCompletions, nil
+	case textRequest.Prompt != nil:
+		return relaymode.Completions, nil
+	case textRequest.Input != nil:
+		return relaymode.Embeddings, nil
+	}
+	return relaymode.Unknown, errors.New("field messages, prompt or input is required")
+}
+
+// countRequestTokens counts the prompt of the request as relaying it would, after the model
+// mapping and forced system prompt of the channel, and estimates the quota it would pre-consume.
+func countRequestTokens(c *gin.Context, meta *meta.Meta, textRequest *model.GeneralOpenAIRequest, relayMode int) *TokenizeResponse {
+	ctx := c.Request.Context()
+	originalModel := textRequest.Model
+	textRequest.Model, _ = getMappedModelName(textRequest.Model, meta.ModelMapping)
+	setSystemPrompt(ctx, textRequest, meta.ForcedSystemPrompt)
+	modelRatio := billingratio.GetModelRatio(textRequest.Model, meta.ChannelType)
+	groupRatio := billingratio.GetGroupRatio(meta.Group)
+	ratio := modelRatio * groupRatio
+	promptTokens := getPromptTokens(textRequest, relayMode)
+	preConsumedQuota := getPreConsumedQuota(textRequest, promptTokens, ratio)
+	if relayMode == relaymode.Embeddings {
+		// relaying leaves embeddings to the usage of the upstream, they are only counted here
+		promptTokens = openai.CountTokenInput(textRequest.ParseInput(), textRequest.Model)
+	}
+	return &TokenizeResponse{
+		Model:            originalModel,
+		PromptTokens:     promptTokens,
+		PreConsumedQuota: preConsumedQuota,
+		ModelRatio:       modelRatio,
+		GroupRatio:       groupRatio,
+		Ratio:            ratio,
+	}
+}
+
+// RelayTokenizeHelper answers how a chat completions, completions or embeddings request would be
+// counted, without relaying it.
+func RelayTokenizeHelper(c *gin.Context) *model.ErrorWithStatusCode {
+	ctx := c.Request.Context()
+	meta := meta.GetByContext(c)
+	textRequest := &model.GeneralOpenAIRequest{}
+	if err := common.UnmarshalBodyReusable(c, textRequest); err != nil {
+		return openai.ErrorWrapper(err, "invalid_tokenize_request", http.StatusBadRequest)
+	}
+	relayMode, err := getTokenizeRelayMode(textRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_tokenize_request", http.StatusBadRequest)
+	}
+	if err = validator.ValidateTextRequest(textRequest, relayMode); err != nil {
+		logger.Errorf(ctx, "ValidateTextRequest failed: %s", err.Error())
+		return openai.ErrorWrapper(err, "invalid_tokenize_request", http.StatusBadRequest)
+	}
+	c.JSON(http.StatusOK, countRequestTokens(c, meta, textRequest, relayMode))
+	return nil
+}
+
+// RelayCountTokensHelper serves an Anthropic count_tokens request, counting the messages as a
+// Messages API request relayed through chat completions would be.
+// https://docs.anthropic.com/en/api/messages-count-tokens
+func RelayCountTokensHelper(c *gin.Context) *model.ErrorWithStatusCode {
+	meta := meta.GetByContext(c)
+	messagesRequest := &anthropic.IngressRequest{}
+	if err := common.UnmarshalBodyReusable(c, messagesRequest); err != nil {
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	textRequest, err := anthropic.ConvertIngressRequest(messagesRequest)
+	if err != nil {
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	if err = validator.ValidateTextRequest(textRequest, relaymode.ChatCompletions); err != nil {
+		return openai.ErrorWrapper(err, "invalid_messages_request", http.StatusBadRequest)
+	}
+	count := countRequestTokens(c, meta, textRequest, relaymode.ChatCompletions)
+	c.JSON(http.StatusOK, anthropic.CountTokensResponse{InputTokens: count.PromptTokens})
+	return nil
+}
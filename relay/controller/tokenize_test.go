@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/ctxkey"
+	"github.com/LeXwDeX/one-api/relay/adaptor/anthropic"
+	"github.com/LeXwDeX/one-api/relay/adaptor/openai"
+	relaymodel "github.com/LeXwDeX/one-api/relay/model"
+)
+
+func newTokenizeContext(path string, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("POST", path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(ctxkey.Group, "default")
+	c.Set(ctxkey.ModelMapping, map[string]string{"my-model": "gpt-3.5-turbo"})
+	return c, recorder
+}
+
+func TestRelayTokenizeHelper(t *testing.T) {
+	openai.InitTokenEncoders()
+
+	c, recorder := newTokenizeContext("/v1/tokenize", `{"model":"my-model","max_tokens":100,"messages":[{"role":"user","content":"hello world"}]}`)
+	require.Nil(t, RelayTokenizeHelper(c))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var response TokenizeResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	promptTokens := openai.CountTokenMessages([]relaymodel.Message{{Role: "user", Content: "hello world"}}, "gpt-3.5-turbo")
+	assert.Equal(t, TokenizeResponse{
+		Model:            "my-model",
+		PromptTokens:     promptTokens,
+		PreConsumedQuota: int64(float64(config.PreConsumedQuota+int64(promptTokens)+100) * 0.25),
+		ModelRatio:       0.25,
+		GroupRatio:       1,
+		Ratio:            0.25,
+	}, response)
+
+	c, recorder = newTokenizeContext("/v1/tokenize", `{"model":"gpt-3.5-turbo","input":["hello world","hello"]}`)
+	require.Nil(t, RelayTokenizeHelper(c))
+	response = TokenizeResponse{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.PromptTokens)
+	// relaying pre-consumes nothing for the input of embeddings
+	assert.Equal(t, int64(float64(config.PreConsumedQuota)*0.25), response.PreConsumedQuota)
+
+	c, _ = newTokenizeContext("/v1/tokenize", `{"model":"gpt-3.5-turbo"}`)
+	bizErr := RelayTokenizeHelper(c)
+	require.NotNil(t, bizErr)
+	assert.Equal(t, http.StatusBadRequest, bizErr.StatusCode)
+}
+
+func TestRelayCountTokensHelper(t *testing.T) {
+	openai.InitTokenEncoders()
+
+	c, recorder := newTokenizeContext("/v1/messages/count_tokens", `{"model":"my-model","messages":[{"role":"user","content":"hello world"}]}`)
+	require.Nil(t, RelayCountTokensHelper(c))
+	var response anthropic.CountTokensResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, openai.CountTokenMessages([]relaymodel.Message{{Role: "user", Content: "hello world"}}, "gpt-3.5-turbo"), response.InputTokens)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/LeXwDeX/one-api/common/ctxkey"
 	"github.com/LeXwDeX/one-api/model"
 	"github.com/LeXwDeX/one-api/relay/channeltype"
+	"github.com/LeXwDeX/one-api/relay/ratelimit"
 	"github.com/LeXwDeX/one-api/relay/relaymode"
+	"github.com/LeXwDeX/one-api/relay/responsecache"
 )
 
 type Meta struct {
@@ -35,6 +37,18 @@ type Meta struct {
 	PromptTokens       int // only for DoResponse
 	ForcedSystemPrompt string
 	StartTime          time.Time
+	// RateLimitScopes are the RPM/TPM budgets the request is charged against
+	RateLimitScopes []ratelimit.Scope
+	// IsBatch is set on requests relayed by the Batch API, which are billed at the batch ratio
+	IsBatch bool
+	// ResponseCacheEnabled is set when the token or the group opted in to the response cache
+	ResponseCacheEnabled bool
+	// IsResponseCacheHit is set when the response was replayed from the response cache
+	IsResponseCacheHit bool
+	// IsClientAborted is set when the client went away before the response was complete
+	IsClientAborted bool
+	// IsFallback is set when the request is served by the fallback model of OriginModelName
+	IsFallback bool
 }
 
 func GetByContext(c *gin.Context) *Meta {
@@ -53,6 +67,12 @@ func GetByContext(c *gin.Context) *Meta {
 		RequestURLPath:     c.Request.URL.String(),
 		ForcedSystemPrompt: c.GetString(ctxkey.SystemPrompt),
 		StartTime:          time.Now(),
+		IsBatch:            c.GetBool(ctxkey.Batch),
+		IsFallback:         c.GetString(ctxkey.FallbackModel) != "",
+	}
+	meta.ResponseCacheEnabled = c.GetBool(ctxkey.TokenResponseCache) || responsecache.IsGroupEnabled(meta.Group)
+	if scopes, ok := c.Get(ctxkey.RateLimitScopes); ok {
+		meta.RateLimitScopes = scopes.([]ratelimit.Scope)
 	}
 	cfg, ok := c.Get(ctxkey.Config)
 	if ok {
@@ -64,3 +84,25 @@ func GetByContext(c *gin.Context) *Meta {
 	meta.APIType = channeltype.ToAPIType(meta.ChannelType)
 	return &meta
 }
+
+// GetByChannel builds the meta of a request made outside of any client request,
+// e.g. to manage resources kept by the upstream on behalf of our users.
+func GetByChannel(channel *model.Channel) *Meta {
+	cfg, _ := channel.LoadConfig()
+	if channel.Type == channeltype.Azure && cfg.APIVersion == "" && channel.Other != nil {
+		cfg.APIVersion = *channel.Other
+	}
+	meta := Meta{
+		ChannelType: channel.Type,
+		ChannelId:   channel.Id,
+		BaseURL:     channel.GetBaseURL(),
+		APIKey:      channel.PickKey(),
+		Config:      cfg,
+		StartTime:   time.Now(),
+	}
+	if meta.BaseURL == "" {
+		meta.BaseURL = channeltype.ChannelBaseURLs[meta.ChannelType]
+	}
+	meta.APIType = channeltype.ToAPIType(meta.ChannelType)
+	return &meta
+}
@@ -87,3 +87,23 @@ func (r GeneralOpenAIRequest) ParseInput() []string {
 	}
 	return input
 }
+
+// reasoningBudgets are the thinking token budgets the reasoning efforts stand for, with the
+// providers that take a budget instead of an effort.
+var reasoningBudgets = map[string]int{
+	"none":    0,
+	"minimal": 1024,
+	"low":     4096,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// GetThinkingBudget returns the thinking token budget for the reasoning effort of the request.
+// ok is false when no known effort was asked for; a budget of 0 asks for no thinking at all.
+func (r GeneralOpenAIRequest) GetThinkingBudget() (budget int, ok bool) {
+	if r.ReasoningEffort == nil {
+		return 0, false
+	}
+	budget, ok = reasoningBudgets[*r.ReasoningEffort]
+	return budget, ok
+}
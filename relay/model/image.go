@@ -1,5 +1,7 @@
 package model
 
+import "mime/multipart"
+
 type ImageRequest struct {
 	Model          string `json:"model"`
 	Prompt         string `json:"prompt" binding:"required"`
@@ -10,3 +12,11 @@ type ImageRequest struct {
 	Style          string `json:"style,omitempty"`
 	User           string `json:"user,omitempty"`
 }
+
+// ImageEditRequest is the multipart form of /v1/images/edits and /v1/images/variations.
+type ImageEditRequest struct {
+	ImageRequest
+	Image []*multipart.FileHeader // gpt-image-1 takes several images as image[]
+	Mask  *multipart.FileHeader
+	Form  *multipart.Form // the whole form, relayed as is to OpenAI-compatible channels
+}
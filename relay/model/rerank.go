@@ -0,0 +1,69 @@
+package model
+
+// RerankRequest is the Jina-compatible rerank request, also taken by SiliconFlow and,
+// once converted, by Cohere.
+// https://jina.ai/reranker/
+type RerankRequest struct {
+	Model           string `json:"model"`
+	Query           string `json:"query"`
+	Documents       []any  `json:"documents"` // strings, or objects such as {"text": "..."}
+	TopN            int    `json:"top_n,omitempty"`
+	ReturnDocuments *bool  `json:"return_documents,omitempty"`
+	MaxChunksPerDoc int    `json:"max_chunks_per_doc,omitempty"`
+	OverlapTokens   int    `json:"overlap_tokens,omitempty"`
+}
+
+type RerankResponse struct {
+	Id      string         `json:"id,omitempty"`
+	Model   string         `json:"model,omitempty"`
+	Results []RerankResult `json:"results"`
+	Usage   *Usage         `json:"usage,omitempty"` // Jina
+	Meta    *RerankMeta    `json:"meta,omitempty"`  // Cohere and SiliconFlow
+}
+
+type RerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       any     `json:"document,omitempty"`
+}
+
+type RerankMeta struct {
+	BilledUnits *RerankBilledUnits `json:"billed_units,omitempty"`
+	Tokens      *RerankTokens      `json:"tokens,omitempty"`
+}
+
+type RerankBilledUnits struct {
+	SearchUnits  int `json:"search_units,omitempty"`
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+}
+
+type RerankTokens struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// GetSearchUnits returns the search units billed by Cohere, 0 for token-billed upstreams.
+func (r *RerankResponse) GetSearchUnits() int {
+	if r == nil || r.Meta == nil || r.Meta.BilledUnits == nil {
+		return 0
+	}
+	return r.Meta.BilledUnits.SearchUnits
+}
+
+// GetTokens returns the tokens counted by the upstream, whichever way it reports them.
+func (r *RerankResponse) GetTokens() int {
+	switch {
+	case r == nil:
+		return 0
+	case r.Usage != nil && r.Usage.TotalTokens > 0:
+		return r.Usage.TotalTokens
+	case r.Usage != nil && r.Usage.PromptTokens > 0:
+		return r.Usage.PromptTokens
+	case r.Meta != nil && r.Meta.Tokens != nil:
+		return r.Meta.Tokens.InputTokens + r.Meta.Tokens.OutputTokens
+	case r.Meta != nil && r.Meta.BilledUnits != nil:
+		return r.Meta.BilledUnits.InputTokens + r.Meta.BilledUnits.OutputTokens
+	}
+	return 0
+}
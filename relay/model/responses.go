@@ -0,0 +1,175 @@
+package model
+
+// https://platform.openai.com/docs/api-reference/responses
+
+type ResponsesRequest struct {
+	Model              string                `json:"model"`
+	Input              any                   `json:"input,omitempty"`
+	Instructions       string                `json:"instructions,omitempty"`
+	MaxOutputTokens    int                   `json:"max_output_tokens,omitempty"`
+	Temperature        *float64              `json:"temperature,omitempty"`
+	TopP               *float64              `json:"top_p,omitempty"`
+	Stream             bool                  `json:"stream,omitempty"`
+	Tools              []ResponsesTool       `json:"tools,omitempty"`
+	ToolChoice         any                   `json:"tool_choice,omitempty"`
+	ParallelToolCalls  *bool                 `json:"parallel_tool_calls,omitempty"`
+	PreviousResponseId string                `json:"previous_response_id,omitempty"`
+	Reasoning          *ResponsesReasoning   `json:"reasoning,omitempty"`
+	Text               *ResponsesTextOptions `json:"text,omitempty"`
+	Store              *bool                 `json:"store,omitempty"`
+	Metadata           any                   `json:"metadata,omitempty"`
+	User               string                `json:"user,omitempty"`
+}
+
+type ResponsesTool struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+type ResponsesReasoning struct {
+	Effort  *string `json:"effort,omitempty"`
+	Summary *string `json:"summary,omitempty"`
+}
+
+type ResponsesTextOptions struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+type ResponsesTextFormat struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// ResponsesInputItem is one element of an array-form `input`.
+// Messages may omit `type`, in which case `role` identifies them.
+type ResponsesInputItem struct {
+	Type    string `json:"type,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Content any    `json:"content,omitempty"`
+	// function_call & function_call_output
+	Id        string `json:"id,omitempty"`
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    any    `json:"output,omitempty"`
+}
+
+type ResponsesResponse struct {
+	Id                string                      `json:"id"`
+	Object            string                      `json:"object"`
+	CreatedAt         int64                       `json:"created_at"`
+	Status            string                      `json:"status"`
+	Model             string                      `json:"model"`
+	Output            []ResponsesOutputItem       `json:"output"`
+	IncompleteDetails *ResponsesIncompleteDetails `json:"incomplete_details"`
+	Usage             *ResponsesUsage             `json:"usage,omitempty"`
+	Error             *ResponsesError             `json:"error"`
+}
+
+type ResponsesOutputItem struct {
+	Type    string                   `json:"type"`
+	Id      string                   `json:"id"`
+	Status  string                   `json:"status,omitempty"`
+	Role    string                   `json:"role,omitempty"`
+	Content []ResponsesOutputContent `json:"content,omitempty"`
+	Summary []ResponsesOutputContent `json:"summary,omitempty"`
+	// function_call
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type ResponsesOutputContent struct {
+	Type        string `json:"type"`
+	Text        string `json:"text"`
+	Annotations []any  `json:"annotations,omitempty"`
+}
+
+type ResponsesIncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+type ResponsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResponsesUsage struct {
+	InputTokens         int                           `json:"input_tokens"`
+	OutputTokens        int                           `json:"output_tokens"`
+	TotalTokens         int                           `json:"total_tokens"`
+	InputTokensDetails  *ResponsesInputTokensDetails  `json:"input_tokens_details,omitempty"`
+	OutputTokensDetails *ResponsesOutputTokensDetails `json:"output_tokens_details,omitempty"`
+}
+
+type ResponsesInputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type ResponsesOutputTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// ResponsesStreamEvent is the payload of a Responses API server-sent event.
+// Only the fields relevant to the event type are set.
+type ResponsesStreamEvent struct {
+	Type           string                  `json:"type"`
+	SequenceNumber int                     `json:"sequence_number"`
+	Response       *ResponsesResponse      `json:"response,omitempty"`
+	OutputIndex    *int                    `json:"output_index,omitempty"`
+	ContentIndex   *int                    `json:"content_index,omitempty"`
+	ItemId         string                  `json:"item_id,omitempty"`
+	Item           *ResponsesOutputItem    `json:"item,omitempty"`
+	Part           *ResponsesOutputContent `json:"part,omitempty"`
+	Delta          string                  `json:"delta,omitempty"`
+	Text           *string                 `json:"text,omitempty"`
+	Arguments      *string                 `json:"arguments,omitempty"`
+}
+
+func (u *ResponsesUsage) ToUsage() *Usage {
+	if u == nil {
+		return nil
+	}
+	usage := &Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	if u.InputTokensDetails != nil && u.InputTokensDetails.CachedTokens != 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{
+			CachedTokens: u.InputTokensDetails.CachedTokens,
+		}
+	}
+	if u.OutputTokensDetails != nil && u.OutputTokensDetails.ReasoningTokens != 0 {
+		usage.CompletionTokensDetails = &CompletionTokensDetails{
+			ReasoningTokens: u.OutputTokensDetails.ReasoningTokens,
+		}
+	}
+	return usage
+}
+
+func (u *Usage) ToResponsesUsage() *ResponsesUsage {
+	if u == nil {
+		return nil
+	}
+	responsesUsage := &ResponsesUsage{
+		InputTokens:         u.PromptTokens,
+		OutputTokens:        u.CompletionTokens,
+		TotalTokens:         u.TotalTokens,
+		InputTokensDetails:  &ResponsesInputTokensDetails{CachedTokens: u.GetCachedTokens()},
+		OutputTokensDetails: &ResponsesOutputTokensDetails{},
+	}
+	if u.CompletionTokensDetails != nil {
+		responsesUsage.OutputTokensDetails.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return responsesUsage
+}
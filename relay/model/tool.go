@@ -1,6 +1,7 @@
 package model
 
 type Tool struct {
+	Index    *int     `json:"index,omitempty"` // only set in stream deltas
 	Id       string   `json:"id,omitempty"`
 	Type     string   `json:"type,omitempty"` // when splicing claude tools stream messages, it is empty
 	Function Function `json:"function"`
@@ -0,0 +1,154 @@
+package paramoverride
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/LeXwDeX/one-api/model"
+)
+
+// Matches tells whether any of the overrides applies to the model.
+func Matches(overrides []model.ParamOverride, modelName string) bool {
+	for i := range overrides {
+		if appliesTo(&overrides[i], modelName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply rewrites the JSON request body with the overrides that apply to the model, in order.
+// Within an override fields are renamed first, then deleted, then defaulted and at last forced.
+// Renames all read the request as sent, so that {"a": "b", "b": "a"} swaps the fields.
+// Defaults and forced values are set in path order, so that "a.b" is set within the "a" set before it.
+func Apply(overrides []model.ParamOverride, modelName string, body []byte) ([]byte, error) {
+	if !Matches(overrides, modelName) {
+		return body, nil
+	}
+	var request map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// keep numbers such as seeds as they were sent
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		override := &overrides[i]
+		if !appliesTo(override, modelName) {
+			continue
+		}
+		rename(request, override.Rename)
+		for _, path := range override.Delete {
+			remove(request, path)
+		}
+		for _, path := range sortedPaths(override.Defaults) {
+			if _, ok := get(request, path); !ok {
+				set(request, path, clone(override.Defaults[path]))
+			}
+		}
+		for _, path := range sortedPaths(override.Force) {
+			set(request, path, clone(override.Force[path]))
+		}
+	}
+	return json.Marshal(request)
+}
+
+func rename(request map[string]any, renames map[string]string) {
+	// sorted, so that the last of the fields renamed to the same name wins every time
+	froms := make([]string, 0, len(renames))
+	for from := range renames {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	values := make(map[string]any, len(froms))
+	for _, from := range froms {
+		if value, ok := get(request, from); ok {
+			values[from] = value
+		}
+	}
+	for _, from := range froms {
+		remove(request, from)
+	}
+	for _, from := range froms {
+		if value, ok := values[from]; ok {
+			set(request, renames[from], value)
+		}
+	}
+}
+
+func sortedPaths(values map[string]any) []string {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// clone copies the objects within the value, so that setting a path within them
+// leaves the channel config as it was.
+func clone(value any) any {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+	copied := make(map[string]any, len(object))
+	for k, v := range object {
+		copied[k] = clone(v)
+	}
+	return copied
+}
+
+func appliesTo(override *model.ParamOverride, modelName string) bool {
+	if len(override.Models) == 0 {
+		return true
+	}
+	for _, m := range override.Models {
+		if m == modelName {
+			return true
+		}
+	}
+	return false
+}
+
+// parent returns the object holding the last segment of the path, creating the
+// objects on the way when create is set.
+func parent(request map[string]any, path string, create bool) (map[string]any, string) {
+	segments := strings.Split(path, ".")
+	object := request
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := object[segment].(map[string]any)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			child = make(map[string]any)
+			object[segment] = child
+		}
+		object = child
+	}
+	return object, segments[len(segments)-1]
+}
+
+func get(request map[string]any, path string) (any, bool) {
+	object, key := parent(request, path, false)
+	if object == nil {
+		return nil, false
+	}
+	value, ok := object[key]
+	return value, ok
+}
+
+func set(request map[string]any, path string, value any) {
+	object, key := parent(request, path, true)
+	object[key] = value
+}
+
+func remove(request map[string]any, path string) {
+	object, key := parent(request, path, false)
+	if object != nil {
+		delete(object, key)
+	}
+}
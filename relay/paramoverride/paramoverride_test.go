@@ -0,0 +1,61 @@
+package paramoverride
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/model"
+)
+
+func TestApply(t *testing.T) {
+	overrides := []model.ParamOverride{
+		{
+			Delete:   []string{"parallel_tool_calls", "service_tier"},
+			Rename:   map[string]string{"max_tokens": "max_completion_tokens"},
+			Defaults: map[string]any{"top_p": 0.9, "generationConfig.topK": 40},
+		},
+		{
+			Models: []string{"o3"},
+			Force:  map[string]any{"temperature": 1},
+		},
+	}
+	body := []byte(`{"model":"o3","seed":12345678901234567890,"temperature":0.2,"top_p":0.5,"max_tokens":100,"parallel_tool_calls":true,"service_tier":"flex"}`)
+	jsonData, err := Apply(overrides, "o3", body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"o3","seed":12345678901234567890,"temperature":1,"top_p":0.5,"max_completion_tokens":100,"generationConfig":{"topK":40}}`, string(jsonData))
+
+	jsonData, err = Apply(overrides, "gpt-4o", body)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonData), `"temperature":0.2`)
+
+	// nothing to do for other models
+	assert.False(t, Matches(overrides[1:], "gpt-4o"))
+	jsonData, err = Apply(overrides[1:], "gpt-4o", body)
+	require.NoError(t, err)
+	assert.Equal(t, body, jsonData)
+}
+
+func TestApplyRenameReadsTheRequestAsSent(t *testing.T) {
+	overrides := []model.ParamOverride{{Rename: map[string]string{"a": "b", "b": "c", "x": "y", "z": "y"}}}
+	body := []byte(`{"a":1,"b":2,"x":3,"z":4}`)
+	for i := 0; i < 10; i++ {
+		jsonData, err := Apply(overrides, "o3", body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"b":1,"c":2,"y":4}`, string(jsonData))
+	}
+}
+
+func TestApplyOverlappingPaths(t *testing.T) {
+	overrides := []model.ParamOverride{{
+		Defaults: map[string]any{"a": map[string]any{"b": 1, "c": 2}, "a.b": 3, "a.d": 4},
+		Force:    map[string]any{"x": map[string]any{"y": 1}, "x.y": 2, "x.z": 3},
+	}}
+	for i := 0; i < 10; i++ {
+		jsonData, err := Apply(overrides, "o3", []byte(`{}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":{"b":1,"c":2,"d":4},"x":{"y":2,"z":3}}`, string(jsonData))
+	}
+	assert.Equal(t, map[string]any{"y": 1}, overrides[0].Force["x"])
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+// Limit holds per-minute budgets, 0 means unlimited
+type Limit struct {
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+var groupRateLimitLock sync.RWMutex
+
+// GroupRateLimit is the budget of every user in a group, e.g. {"default": {"rpm": 60, "tpm": 100000}}
+var GroupRateLimit = map[string]Limit{}
+
+func GroupRateLimit2JSONString() string {
+	groupRateLimitLock.RLock()
+	defer groupRateLimitLock.RUnlock()
+	jsonBytes, err := json.Marshal(GroupRateLimit)
+	if err != nil {
+		logger.SysError("error marshalling group rate limit: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupRateLimitByJSONString(jsonStr string) error {
+	groupRateLimitLock.Lock()
+	defer groupRateLimitLock.Unlock()
+	GroupRateLimit = make(map[string]Limit)
+	return json.Unmarshal([]byte(jsonStr), &GroupRateLimit)
+}
+
+func GetGroupRateLimit(name string) Limit {
+	groupRateLimitLock.RLock()
+	defer groupRateLimitLock.RUnlock()
+	return GroupRateLimit[name]
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+// window is the length in seconds of the fixed windows RPM and TPM are counted in
+const window int64 = 60
+
+var inMemoryRateLimiter common.InMemoryRateLimiter
+
+// Scope is one budget a request is charged against, e.g. the token or the user's group.
+type Scope struct {
+	Key   string
+	Limit Limit
+}
+
+// Status describes a budget the way the x-ratelimit-* headers report it.
+type Status struct {
+	Limit      int64
+	Remaining  int64
+	ResetAfter int64 // seconds
+}
+
+type Result struct {
+	Requests *Status // nil if no RPM limit applies
+	Tokens   *Status // nil if no TPM limit applies
+	Exceeded bool
+	// RetryAfter is the number of seconds until the exceeded budget resets
+	RetryAfter int64
+}
+
+// GetScopes returns the budgets that apply to a request made with the given token.
+// Group limits are per user, so one user cannot exhaust the budget of the whole group.
+func GetScopes(tokenId int, tokenLimit Limit, userId int, group string) []Scope {
+	var scopes []Scope
+	if tokenLimit.RPM > 0 || tokenLimit.TPM > 0 {
+		scopes = append(scopes, Scope{Key: fmt.Sprintf("token:%d", tokenId), Limit: tokenLimit})
+	}
+	if groupLimit := GetGroupRateLimit(group); groupLimit.RPM > 0 || groupLimit.TPM > 0 {
+		scopes = append(scopes, Scope{Key: fmt.Sprintf("user:%d", userId), Limit: groupLimit})
+	}
+	return scopes
+}
+
+func add(ctx context.Context, key string, amount int64) (count int64, resetAfter int64, err error) {
+	if !common.RedisEnabled {
+		// It's safe to call multi times.
+		inMemoryRateLimiter.Init(config.RateLimitKeyExpirationDuration)
+		count, resetAfter = inMemoryRateLimiter.Add(key, amount, window)
+		return count, resetAfter, nil
+	}
+	now := time.Now().Unix()
+	windowStart := now - now%window
+	redisKey := fmt.Sprintf("rateLimit:%s:%d", key, windowStart)
+	pipe := common.RDB.TxPipeline()
+	incr := pipe.IncrBy(ctx, redisKey, amount)
+	pipe.Expire(ctx, redisKey, time.Duration(2*window)*time.Second)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+	return incr.Val(), windowStart + window - now, nil
+}
+
+func remaining(limit int64, count int64) int64 {
+	if count >= limit {
+		return 0
+	}
+	return limit - count
+}
+
+// tighter returns the status with less remaining budget
+func tighter(current *Status, status *Status) *Status {
+	if current == nil || status.Remaining < current.Remaining {
+		return status
+	}
+	return current
+}
+
+func (r *Result) exceed(resetAfter int64) {
+	r.Exceeded = true
+	if resetAfter > r.RetryAfter {
+		r.RetryAfter = resetAfter
+	}
+}
+
+// Check counts one request against the RPM of every scope, and rejects the request
+// if any RPM budget is exceeded or any TPM budget is already used up.
+func Check(ctx context.Context, scopes []Scope) (*Result, error) {
+	result := &Result{}
+	for _, scope := range scopes {
+		if scope.Limit.RPM > 0 {
+			count, resetAfter, err := add(ctx, "rpm:"+scope.Key, 1)
+			if err != nil {
+				return nil, err
+			}
+			limit := int64(scope.Limit.RPM)
+			result.Requests = tighter(result.Requests, &Status{Limit: limit, Remaining: remaining(limit, count), ResetAfter: resetAfter})
+			if count > limit {
+				result.exceed(resetAfter)
+			}
+		}
+		if scope.Limit.TPM > 0 {
+			count, resetAfter, err := add(ctx, "tpm:"+scope.Key, 0)
+			if err != nil {
+				return nil, err
+			}
+			limit := int64(scope.Limit.TPM)
+			result.Tokens = tighter(result.Tokens, &Status{Limit: limit, Remaining: remaining(limit, count), ResetAfter: resetAfter})
+			if count >= limit {
+				result.exceed(resetAfter)
+			}
+		}
+	}
+	return result, nil
+}
+
+// RecordTokens charges the tokens actually used by a request against the TPM of its scopes.
+// It runs once the request is over, possibly cancelled by the client, so ctx is only used
+// for logging.
+func RecordTokens(ctx context.Context, scopes []Scope, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	for _, scope := range scopes {
+		if scope.Limit.TPM <= 0 {
+			continue
+		}
+		if _, _, err := add(context.Background(), "tpm:"+scope.Key, int64(tokens)); err != nil {
+			logger.Error(ctx, "error recording rate limit tokens: "+err.Error())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common"
+)
+
+func TestCheck(t *testing.T) {
+	common.RedisEnabled = false
+	require.NoError(t, UpdateGroupRateLimitByJSONString(`{"default": {"rpm": 0, "tpm": 100}}`))
+	scopes := GetScopes(1, Limit{RPM: 2}, 1, "default")
+	require.Len(t, scopes, 2)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		result, err := Check(ctx, scopes)
+		require.NoError(t, err)
+		assert.False(t, result.Exceeded)
+		assert.Equal(t, int64(2), result.Requests.Limit)
+		assert.Equal(t, int64(1-i), result.Requests.Remaining)
+		assert.Equal(t, int64(100), result.Tokens.Remaining)
+	}
+	result, err := Check(ctx, scopes)
+	require.NoError(t, err)
+	assert.True(t, result.Exceeded)
+	assert.Positive(t, result.RetryAfter)
+
+	// tokens are only charged to scopes with a TPM limit
+	scopes = GetScopes(2, Limit{}, 2, "default")
+	require.Len(t, scopes, 1)
+	RecordTokens(ctx, scopes, 100)
+	result, err = Check(ctx, scopes)
+	require.NoError(t, err)
+	assert.True(t, result.Exceeded)
+	assert.Nil(t, result.Requests)
+	assert.Equal(t, int64(0), result.Tokens.Remaining)
+}
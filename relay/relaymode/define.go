@@ -13,4 +13,18 @@ const (
 	AudioTranslation
 	// Proxy is a special relay mode for proxying requests to custom upstream
 	Proxy
+	// Responses is the OpenAI Responses API, translated to chat completions for channels that lack it
+	Responses
+	// Messages is the Anthropic Messages API, translated to chat completions
+	Messages
+	// GeminiGenerateContent and GeminiEmbedContent are the Gemini-native endpoints, translated likewise
+	GeminiGenerateContent
+	GeminiEmbedContent
+	// ImagesEdits and ImagesVariations take multipart/form-data with the uploaded images
+	ImagesEdits
+	ImagesVariations
+	// Realtime is the WebSocket Realtime API, proxied frame by frame
+	Realtime
+	// Rerank is the Jina-compatible rerank API
+	Rerank
 )
@@ -0,0 +1,133 @@
+package responsecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/common/config"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+// MaxEntrySize bounds the responses worth caching; longer ones are relayed but not kept.
+const MaxEntrySize = 1 << 20
+
+// maxMemoryEntries bounds the in-memory cache used when Redis is disabled
+const maxMemoryEntries = 10000
+
+// Entry is a response as it was written to the client, replayed byte for byte on a hit.
+type Entry struct {
+	ContentType string       `json:"content_type"`
+	Body        []byte       `json:"body"`
+	Usage       *model.Usage `json:"usage"`
+}
+
+type memoryEntry struct {
+	value    []byte
+	expireAt time.Time
+}
+
+var memoryCache = struct {
+	sync.Mutex
+	entries map[string]memoryEntry
+}{entries: make(map[string]memoryEntry)}
+
+// IsCacheable tells whether the response to a request is deterministic enough to be reused:
+// embeddings always are, completions only when sampled at temperature 0.
+func IsCacheable(relayMode int, request *model.GeneralOpenAIRequest) bool {
+	switch relayMode {
+	case relaymode.Embeddings:
+		return true
+	case relaymode.ChatCompletions, relaymode.Completions:
+		return request.Temperature != nil && *request.Temperature == 0
+	}
+	return false
+}
+
+// IsBypassed honors Cache-Control: no-cache and no-store on the client request.
+func IsBypassed(header http.Header) (noCache bool, noStore bool) {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noCache, noStore = true, true
+		}
+	}
+	return noCache, noStore
+}
+
+// GetKey hashes the normalized request, whose model has been mapped already. Entries are kept
+// per user, so that nobody is served the responses of others.
+func GetKey(userId int, relayMode int, request *model.GeneralOpenAIRequest) (string, error) {
+	normalized := *request
+	normalized.User = ""
+	jsonBytes, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(jsonBytes)
+	return fmt.Sprintf("responseCache:%d:%d:%s", userId, relayMode, hex.EncodeToString(hash[:])), nil
+}
+
+func Get(ctx context.Context, key string) (*Entry, bool) {
+	var value []byte
+	if common.RedisEnabled {
+		result, err := common.RDB.Get(ctx, key).Bytes()
+		if err != nil {
+			return nil, false
+		}
+		value = result
+	} else {
+		memoryCache.Lock()
+		entry, ok := memoryCache.entries[key]
+		memoryCache.Unlock()
+		if !ok || time.Now().After(entry.expireAt) {
+			return nil, false
+		}
+		value = entry.value
+	}
+	var entry Entry
+	if err := json.Unmarshal(value, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func Set(ctx context.Context, key string, entry *Entry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(config.ResponseCacheTTL) * time.Second
+	if common.RedisEnabled {
+		return common.RDB.Set(ctx, key, value, ttl).Err()
+	}
+	memoryCache.Lock()
+	defer memoryCache.Unlock()
+	now := time.Now()
+	if len(memoryCache.entries) >= maxMemoryEntries {
+		for k, e := range memoryCache.entries {
+			if now.After(e.expireAt) {
+				delete(memoryCache.entries, k)
+			}
+		}
+	}
+	if len(memoryCache.entries) >= maxMemoryEntries {
+		// still full of live entries, make room for the new one
+		for k := range memoryCache.entries {
+			delete(memoryCache.entries, k)
+			break
+		}
+	}
+	memoryCache.entries[key] = memoryEntry{value: value, expireAt: now.Add(ttl)}
+	return nil
+}
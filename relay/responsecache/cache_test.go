@@ -0,0 +1,90 @@
+package responsecache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common"
+	"github.com/LeXwDeX/one-api/relay/model"
+	"github.com/LeXwDeX/one-api/relay/relaymode"
+)
+
+func TestIsCacheable(t *testing.T) {
+	zero, one := 0.0, 1.0
+	assert.True(t, IsCacheable(relaymode.Embeddings, &model.GeneralOpenAIRequest{}))
+	assert.True(t, IsCacheable(relaymode.ChatCompletions, &model.GeneralOpenAIRequest{Temperature: &zero}))
+	assert.False(t, IsCacheable(relaymode.ChatCompletions, &model.GeneralOpenAIRequest{Temperature: &one}))
+	assert.False(t, IsCacheable(relaymode.ChatCompletions, &model.GeneralOpenAIRequest{}))
+	assert.False(t, IsCacheable(relaymode.Responses, &model.GeneralOpenAIRequest{Temperature: &zero}))
+}
+
+func TestIsBypassed(t *testing.T) {
+	header := http.Header{}
+	noCache, noStore := IsBypassed(header)
+	assert.False(t, noCache)
+	assert.False(t, noStore)
+	header.Set("Cache-Control", "max-age=0, No-Cache")
+	noCache, noStore = IsBypassed(header)
+	assert.True(t, noCache)
+	assert.False(t, noStore)
+	header.Set("Cache-Control", "no-store")
+	noCache, noStore = IsBypassed(header)
+	assert.True(t, noCache)
+	assert.True(t, noStore)
+}
+
+func TestGetKey(t *testing.T) {
+	request := &model.GeneralOpenAIRequest{Model: "gpt-4o", Input: "hello", User: "alice"}
+	key, err := GetKey(1, relaymode.Embeddings, request)
+	require.NoError(t, err)
+
+	// the end user is no part of the request semantics
+	other := *request
+	other.User = "bob"
+	otherKey, err := GetKey(1, relaymode.Embeddings, &other)
+	require.NoError(t, err)
+	assert.Equal(t, key, otherKey)
+
+	other.Model = "gpt-4o-mini"
+	otherKey, _ = GetKey(1, relaymode.Embeddings, &other)
+	assert.NotEqual(t, key, otherKey)
+	otherKey, _ = GetKey(2, relaymode.Embeddings, request)
+	assert.NotEqual(t, key, otherKey)
+}
+
+func TestMemoryCache(t *testing.T) {
+	common.RedisEnabled = false
+	ctx := context.Background()
+	_, ok := Get(ctx, "responseCache:test:missing")
+	assert.False(t, ok)
+
+	entry := &Entry{ContentType: "application/json", Body: []byte(`{"id":"1"}`), Usage: &model.Usage{PromptTokens: 1, TotalTokens: 1}}
+	require.NoError(t, Set(ctx, "responseCache:test:hit", entry))
+	cached, ok := Get(ctx, "responseCache:test:hit")
+	require.True(t, ok)
+	assert.Equal(t, entry, cached)
+}
+
+func TestRecorder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	recorder := NewRecorder(c.Writer)
+	recorder.Header().Set("Content-Type", "text/event-stream")
+	_, _ = recorder.WriteString("data: {}\n\n")
+	_, _ = recorder.Write([]byte("data: [DONE]\n\n"))
+	entry, ok := recorder.Entry()
+	require.True(t, ok)
+	assert.Equal(t, "text/event-stream", entry.ContentType)
+	assert.Equal(t, "data: {}\n\ndata: [DONE]\n\n", string(entry.Body))
+
+	_, _ = recorder.WriteString(strings.Repeat("x", MaxEntrySize))
+	_, ok = recorder.Entry()
+	assert.False(t, ok)
+}
@@ -0,0 +1,42 @@
+package responsecache
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+var groupsLock sync.RWMutex
+
+// Groups lists the groups whose users all get the response cache, e.g. ["ci"].
+// Other users opt in per token.
+var Groups = []string{}
+
+func Groups2JSONString() string {
+	groupsLock.RLock()
+	defer groupsLock.RUnlock()
+	jsonBytes, err := json.Marshal(Groups)
+	if err != nil {
+		logger.SysError("error marshalling response cache groups: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupsByJSONString(jsonStr string) error {
+	groupsLock.Lock()
+	defer groupsLock.Unlock()
+	Groups = make([]string, 0)
+	return json.Unmarshal([]byte(jsonStr), &Groups)
+}
+
+func IsGroupEnabled(group string) bool {
+	groupsLock.RLock()
+	defer groupsLock.RUnlock()
+	for _, g := range Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
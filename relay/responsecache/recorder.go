@@ -0,0 +1,53 @@
+package responsecache
+
+import (
+	"bytes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recorder copies what is written to the client, up to MaxEntrySize, so that it can be cached.
+type Recorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func NewRecorder(w gin.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+func (r *Recorder) record(n int, b []byte) {
+	if r.overflow {
+		return
+	}
+	if r.body.Len()+n > MaxEntrySize {
+		r.overflow = true
+		r.body.Reset()
+		return
+	}
+	r.body.Write(b[:n])
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.record(n, b)
+	return n, err
+}
+
+func (r *Recorder) WriteString(s string) (int, error) {
+	n, err := r.ResponseWriter.WriteString(s)
+	r.record(n, []byte(s))
+	return n, err
+}
+
+// Entry returns what was written, or false when it was too long to keep.
+func (r *Recorder) Entry() (*Entry, bool) {
+	if r.overflow || r.Status() != 200 || r.body.Len() == 0 {
+		return nil, false
+	}
+	return &Entry{
+		ContentType: r.Header().Get("Content-Type"),
+		Body:        bytes.Clone(r.body.Bytes()),
+	}, true
+}
@@ -0,0 +1,53 @@
+package tokenizer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/LeXwDeX/one-api/common/config"
+)
+
+// Only the OpenAI vocabularies, cl100k_base and o200k_base, are embedded. Llama 3 and Gemma,
+// whose tokenizer Gemini shares, come with their own licenses: llama3.tiktoken and gemma.model
+// are read from config.TokenizerDataDir alone, and those families count with tiktoken without them.
+//
+//go:embed data/*.gz
+var data embed.FS
+
+// readData returns the content of a tokenizer file, read from config.TokenizerDataDir when it
+// is found there, plain or gzipped, and from the embedded files otherwise.
+func readData(name string) ([]byte, error) {
+	if config.TokenizerDataDir != "" {
+		content, err := os.ReadFile(filepath.Join(config.TokenizerDataDir, name))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return content, err
+		}
+		compressed, err := os.ReadFile(filepath.Join(config.TokenizerDataDir, name+".gz"))
+		if err == nil {
+			return gunzip(compressed)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	compressed, err := data.ReadFile("data/" + name + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	return gunzip(compressed)
+}
+
+func gunzip(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
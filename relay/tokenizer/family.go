@@ -0,0 +1,88 @@
+package tokenizer
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Llama 3 splits text as cl100k_base does.
+// https://github.com/meta-llama/llama3/blob/main/llama/tokenizer.py
+const llama3Pattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+var llama3Regexp = regexp.MustCompile(`llama-?3`)
+
+func init() {
+	// Qwen builds upon the cl100k_base vocabulary, so it is left to tiktoken like every family
+	// not registered here.
+	Register(&Family{
+		Name:        "claude",
+		Match:       containsAny("claude"),
+		ImageTokens: claudeImageTokens,
+	})
+	Register(&Family{
+		Name:  "gemini",
+		Match: containsAny("gemini", "gemma"),
+		Load: func() (Tokenizer, error) {
+			return loadSentencePiece("gemma.model")
+		},
+		ImageTokens: geminiImageTokens,
+	})
+	Register(&Family{
+		Name:  "llama3",
+		Match: llama3Regexp.MatchString,
+		Load: func() (Tokenizer, error) {
+			return loadTiktoken("llama3.tiktoken", llama3Pattern)
+		},
+	})
+}
+
+func containsAny(substrs ...string) func(model string) bool {
+	return func(model string) bool {
+		for _, substr := range substrs {
+			if strings.Contains(model, substr) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+const (
+	claudeMaxEdge        = 1568
+	claudeMaxTokens      = 1600
+	claudePixelsPerToken = 750
+)
+
+// https://docs.anthropic.com/en/docs/build-with-claude/vision#calculate-image-costs
+func claudeImageTokens(width, height int) int {
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	// larger images are scaled down, keeping their aspect ratio
+	if longEdge := math.Max(float64(width), float64(height)); longEdge > claudeMaxEdge {
+		ratio := claudeMaxEdge / longEdge
+		width = int(float64(width) * ratio)
+		height = int(float64(height) * ratio)
+	}
+	tokens := int(math.Ceil(float64(width*height) / claudePixelsPerToken))
+	if tokens > claudeMaxTokens {
+		tokens = claudeMaxTokens
+	}
+	return tokens
+}
+
+const (
+	geminiSmallEdge     = 384
+	geminiTileEdge      = 768
+	geminiTokensPerTile = 258
+)
+
+// https://ai.google.dev/gemini-api/docs/tokens#multimodal-tokens
+func geminiImageTokens(width, height int) int {
+	if width <= geminiSmallEdge && height <= geminiSmallEdge {
+		return geminiTokensPerTile
+	}
+	tiles := math.Ceil(float64(width)/geminiTileEdge) * math.Ceil(float64(height)/geminiTileEdge)
+	return int(tiles) * geminiTokensPerTile
+}
@@ -0,0 +1,216 @@
+package tokenizer
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const spaceSymbol = "▁"
+
+// https://github.com/google/sentencepiece/blob/master/src/sentencepiece_model.proto
+const (
+	pieceTypeNormal      = 1
+	pieceTypeUserDefined = 4
+)
+
+// sentencePiece counts with a SentencePiece BPE model, as used by Gemma and Gemini:
+// symbols are merged by the highest piece score, and what is not in the vocabulary
+// falls back to one token per byte.
+type sentencePiece struct {
+	scores map[string]float32
+}
+
+// loadSentencePiece builds a tokenizer from a serialized SentencePiece model.
+func loadSentencePiece(name string) (Tokenizer, error) {
+	content, err := readData(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseSentencePiece(content)
+}
+
+func (s *sentencePiece) Count(text string) int {
+	text = strings.ReplaceAll(text, " ", spaceSymbol)
+	count := 0
+	// merges don't cross words, which start where spaces follow anything else
+	start := 0
+	for i := 1; i < len(text); i++ {
+		if strings.HasPrefix(text[i:], spaceSymbol) && !strings.HasSuffix(text[:i], spaceSymbol) {
+			count += s.countWord(text[start:i])
+			start = i
+		}
+	}
+	return count + s.countWord(text[start:])
+}
+
+func (s *sentencePiece) countWord(word string) int {
+	if word == "" {
+		return 0
+	}
+	if _, ok := s.scores[word]; ok {
+		return 1
+	}
+	// symbols form a linked list, merged pairs are queued by score
+	symbols := make([]string, 0, utf8.RuneCountInString(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+	next := make([]int, len(symbols))
+	prev := make([]int, len(symbols))
+	for i := range symbols {
+		next[i] = i + 1
+		prev[i] = i - 1
+	}
+	queue := &mergeQueue{}
+	push := func(left, right int) {
+		if left < 0 || right >= len(symbols) {
+			return
+		}
+		merged := symbols[left] + symbols[right]
+		if score, ok := s.scores[merged]; ok {
+			heap.Push(queue, merge{left: left, right: right, score: score, merged: merged})
+		}
+	}
+	for i := 0; i+1 < len(symbols); i++ {
+		push(i, i+1)
+	}
+	for queue.Len() > 0 {
+		m := heap.Pop(queue).(merge)
+		// skip merges whose symbols have changed since
+		if symbols[m.left] == "" || symbols[m.right] == "" || next[m.left] != m.right || symbols[m.left]+symbols[m.right] != m.merged {
+			continue
+		}
+		symbols[m.left] = m.merged
+		symbols[m.right] = ""
+		next[m.left] = next[m.right]
+		if next[m.right] < len(symbols) {
+			prev[next[m.right]] = m.left
+		}
+		push(prev[m.left], m.left)
+		push(m.left, next[m.left])
+	}
+	count := 0
+	for _, symbol := range symbols {
+		if symbol == "" {
+			continue
+		}
+		if _, ok := s.scores[symbol]; ok {
+			count++
+		} else {
+			count += len(symbol)
+		}
+	}
+	return count
+}
+
+type merge struct {
+	left, right int
+	score       float32
+	merged      string
+}
+
+type mergeQueue []merge
+
+func (q mergeQueue) Len() int { return len(q) }
+
+func (q mergeQueue) Less(i, j int) bool {
+	return q[i].score > q[j].score || (q[i].score == q[j].score && q[i].left < q[j].left)
+}
+
+func (q mergeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *mergeQueue) Push(x any) { *q = append(*q, x.(merge)) }
+
+func (q *mergeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+var errInvalidModel = errors.New("invalid sentencepiece model")
+
+// parseSentencePiece reads the pieces out of a serialized ModelProto, keeping those
+// that take part in merges.
+func parseSentencePiece(content []byte) (*sentencePiece, error) {
+	s := &sentencePiece{scores: make(map[string]float32)}
+	err := readProtoFields(content, func(field int, value []byte) error {
+		if field != 1 {
+			return nil
+		}
+		var piece string
+		var score float32
+		pieceType := uint64(pieceTypeNormal)
+		err := readProtoFields(value, func(field int, value []byte) error {
+			switch field {
+			case 1:
+				piece = string(value)
+			case 2:
+				score = math.Float32frombits(binary.LittleEndian.Uint32(value))
+			case 3:
+				pieceType, _ = binary.Uvarint(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if pieceType == pieceTypeNormal || pieceType == pieceTypeUserDefined {
+			s.scores[piece] = score
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.scores) == 0 {
+		return nil, errInvalidModel
+	}
+	return s, nil
+}
+
+// readProtoFields calls fn with the number and raw value of each field of a protobuf message.
+func readProtoFields(message []byte, fn func(field int, value []byte) error) error {
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return errInvalidModel
+		}
+		message = message[n:]
+		var value []byte
+		switch key & 7 {
+		case 0: // varint
+			_, n = binary.Uvarint(message)
+			if n <= 0 {
+				return errInvalidModel
+			}
+			value, message = message[:n], message[n:]
+		case 1: // 64-bit
+			if len(message) < 8 {
+				return errInvalidModel
+			}
+			value, message = message[:8], message[8:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return errInvalidModel
+			}
+			value, message = message[n:n+int(length)], message[n+int(length):]
+		case 5: // 32-bit
+			if len(message) < 4 {
+				return errInvalidModel
+			}
+			value, message = message[:4], message[4:]
+		default:
+			return errInvalidModel
+		}
+		if err := fn(int(key>>3), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// tiktokenTokenizer counts with a byte pair encoding in the tiktoken format, as used by Llama 3.
+type tiktokenTokenizer struct {
+	encoder *tiktoken.Tiktoken
+}
+
+func (t *tiktokenTokenizer) Count(text string) int {
+	return len(t.encoder.EncodeOrdinary(text))
+}
+
+// loadTiktoken builds a tokenizer from a file of base64 tokens followed by their rank.
+func loadTiktoken(name string, pattern string) (Tokenizer, error) {
+	ranks, err := new(BpeLoader).LoadTiktokenBpe(name)
+	if err != nil {
+		return nil, err
+	}
+	bpe, err := tiktoken.NewCoreBPE(ranks, map[string]int{}, pattern)
+	if err != nil {
+		return nil, err
+	}
+	encoding := &tiktoken.Encoding{
+		Name:           name,
+		PatStr:         pattern,
+		MergeableRanks: ranks,
+		SpecialTokens:  map[string]int{},
+	}
+	return &tiktokenTokenizer{encoder: tiktoken.NewTiktoken(bpe, encoding, map[string]any{})}, nil
+}
+
+func parseTiktokenRanks(content []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, found := bytes.Cut(line, []byte(" "))
+		if !found {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(decoded)], err = strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ranks, nil
+}
+
+// BpeLoader loads the encodings tiktoken asks for from the tokenizer files instead of downloading
+// them, so that counting works offline.
+type BpeLoader struct{}
+
+func (l *BpeLoader) LoadTiktokenBpe(tiktokenBpeFile string) (map[string]int, error) {
+	name := path.Base(tiktokenBpeFile)
+	content, err := readData(name)
+	if err != nil {
+		return nil, err
+	}
+	ranks, err := parseTiktokenRanks(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return ranks, nil
+}
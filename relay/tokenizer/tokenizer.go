@@ -0,0 +1,72 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/LeXwDeX/one-api/common/logger"
+)
+
+// Tokenizer counts the tokens a model splits a text into.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Family is a group of models sharing a tokenizer and a way of pricing images.
+type Family struct {
+	Name string
+	// Match tells whether the lowercased model name belongs to the family.
+	Match func(model string) bool
+	// Load returns the tokenizer of the family, it is called once on first use.
+	// Without it text is counted with tiktoken.
+	Load func() (Tokenizer, error)
+	// ImageTokens returns the tokens an image of the given size costs.
+	// Without it images are counted as OpenAI does.
+	ImageTokens func(width, height int) int
+
+	once      sync.Once
+	tokenizer Tokenizer
+}
+
+// Tokenizer returns the tokenizer of the family, loading it the first time.
+// ok is false when the family has none or it failed to load.
+func (f *Family) Tokenizer() (tokenizer Tokenizer, ok bool) {
+	if f.Load == nil {
+		return nil, false
+	}
+	f.once.Do(func() {
+		var err error
+		f.tokenizer, err = f.Load()
+		if errors.Is(err, fs.ErrNotExist) {
+			logger.SysLog(fmt.Sprintf("%s tokenizer file not found, counting with tiktoken", f.Name))
+			f.tokenizer = nil
+		} else if err != nil {
+			logger.SysError(fmt.Sprintf("failed to load %s tokenizer, falling back to tiktoken: %s", f.Name, err.Error()))
+			f.tokenizer = nil
+		}
+	})
+	return f.tokenizer, f.tokenizer != nil
+}
+
+// families won't grow after initialization
+var families []*Family
+
+// Register adds a family, taking precedence over the families registered before it.
+// It is meant to be called from init functions.
+func Register(family *Family) {
+	families = append([]*Family{family}, families...)
+}
+
+// GetFamily returns the family the model belongs to, or nil when it belongs to none.
+func GetFamily(model string) *Family {
+	model = strings.ToLower(model)
+	for _, family := range families {
+		if family.Match(model) {
+			return family
+		}
+	}
+	return nil
+}
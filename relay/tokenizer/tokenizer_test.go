@@ -0,0 +1,169 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeXwDeX/one-api/common/config"
+)
+
+func TestGetFamily(t *testing.T) {
+	cases := map[string]string{
+		"claude-3-5-sonnet-20241022":            "claude",
+		"gemini-1.5-pro":                        "gemini",
+		"google/gemma-2-9b-it":                  "gemini",
+		"llama3-8b-8192":                        "llama3",
+		"meta-llama/Meta-Llama-3.1-8B-Instruct": "llama3",
+		"@cf/meta/llama-3-8b-instruct":          "llama3",
+	}
+	for model, name := range cases {
+		family := GetFamily(model)
+		require.NotNil(t, family, model)
+		assert.Equal(t, name, family.Name, model)
+	}
+	assert.Nil(t, GetFamily("gpt-4o"))
+	assert.Nil(t, GetFamily("llama-2-7b-chat"))
+	assert.Nil(t, GetFamily("qwen-turbo"))
+}
+
+func TestRegisterTakesPrecedence(t *testing.T) {
+	saved := families
+	defer func() { families = saved }()
+	Register(&Family{Name: "claude-custom", Match: containsAny("claude-custom")})
+	assert.Equal(t, "claude-custom", GetFamily("claude-custom-1").Name)
+	assert.Equal(t, "claude", GetFamily("claude-3-haiku").Name)
+}
+
+// useDataDir points the tokenizer files to a temporary directory for the duration of the test.
+func useDataDir(t *testing.T) string {
+	dir := t.TempDir()
+	saved := config.TokenizerDataDir
+	config.TokenizerDataDir = dir
+	t.Cleanup(func() { config.TokenizerDataDir = saved })
+	return dir
+}
+
+func TestLlama3Tokenizer(t *testing.T) {
+	dir := useDataDir(t)
+	var lines []string
+	for i := 0; i < 256; i++ {
+		lines = append(lines, base64.StdEncoding.EncodeToString([]byte{byte(i)})+" "+strconv.Itoa(i))
+	}
+	for i, token := range []string{"he", "ll", "hell", "hello"} {
+		lines = append(lines, base64.StdEncoding.EncodeToString([]byte(token))+" "+strconv.Itoa(256+i))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "llama3.tiktoken"), []byte(strings.Join(lines, "\n")), 0o644))
+
+	tokenizer, err := loadTiktoken("llama3.tiktoken", llama3Pattern)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tokenizer.Count("hello"))
+	// hello, " ", w, o, r, l, d
+	assert.Equal(t, 7, tokenizer.Count("hello world"))
+	assert.Equal(t, 0, tokenizer.Count(""))
+}
+
+// sentencePieceModel serializes a ModelProto holding the given normal pieces.
+func sentencePieceModel(scores map[string]float32) []byte {
+	var model []byte
+	for piece, score := range scores {
+		var message []byte
+		message = binary.AppendUvarint(message, 1<<3|2)
+		message = binary.AppendUvarint(message, uint64(len(piece)))
+		message = append(message, piece...)
+		message = binary.AppendUvarint(message, 2<<3|5)
+		message = binary.LittleEndian.AppendUint32(message, math.Float32bits(score))
+		message = binary.AppendUvarint(message, 3<<3|0)
+		message = binary.AppendUvarint(message, pieceTypeNormal)
+		model = binary.AppendUvarint(model, 1<<3|2)
+		model = binary.AppendUvarint(model, uint64(len(message)))
+		model = append(model, message...)
+	}
+	return model
+}
+
+func TestGeminiTokenizer(t *testing.T) {
+	dir := useDataDir(t)
+	model := sentencePieceModel(map[string]float32{"h": 0, "i": 0, "▁": 0, "hi": -1, "▁hi": -2})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gemma.model"), model, 0o644))
+
+	tokenizer, err := loadSentencePiece("gemma.model")
+	require.NoError(t, err)
+	assert.Equal(t, 2, tokenizer.Count("hi hi"))
+	assert.Equal(t, 0, tokenizer.Count(""))
+}
+
+func TestTokenizerFileMissing(t *testing.T) {
+	useDataDir(t)
+	// the vocabularies with their own license are not embedded
+	_, err := loadTiktoken("llama3.tiktoken", llama3Pattern)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	family := &Family{Name: "gemini", Load: func() (Tokenizer, error) {
+		return loadSentencePiece("gemma.model")
+	}}
+	_, ok := family.Tokenizer()
+	assert.False(t, ok)
+}
+
+func TestSentencePieceCount(t *testing.T) {
+	s := &sentencePiece{scores: map[string]float32{
+		"a": 0, "b": 0, "c": 0, "▁": 0,
+		"ab": -1, "abc": -2, "bc": -3, "▁a": -4,
+	}}
+	assert.Equal(t, 1, s.Count("abc"))
+	// ▁,abc as ab outscores ▁a
+	assert.Equal(t, 3, s.Count("abc abc"))
+	// words are merged apart
+	assert.Equal(t, 4, s.Count("ab c a"))
+	// bytes out of the vocabulary are counted one by one
+	assert.Equal(t, 4, s.Count("a你"))
+}
+
+func TestClaudeHasNoTokenizer(t *testing.T) {
+	_, ok := GetFamily("claude-3-opus").Tokenizer()
+	assert.False(t, ok)
+}
+
+func TestClaudeImageTokens(t *testing.T) {
+	assert.Equal(t, 1334, claudeImageTokens(1000, 1000))
+	// scaled down to 1568x784
+	assert.Equal(t, 1600, claudeImageTokens(4000, 2000))
+	assert.Equal(t, 54, claudeImageTokens(200, 200))
+}
+
+func TestGeminiImageTokens(t *testing.T) {
+	assert.Equal(t, 258, geminiImageTokens(384, 200))
+	assert.Equal(t, 258, geminiImageTokens(768, 768))
+	assert.Equal(t, 4*258, geminiImageTokens(1024, 1024))
+}
+
+func TestBpeLoader(t *testing.T) {
+	ranks, err := new(BpeLoader).LoadTiktokenBpe("https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken")
+	require.NoError(t, err)
+	assert.Len(t, ranks, 100256)
+	_, err = new(BpeLoader).LoadTiktokenBpe("https://openaipublic.blob.core.windows.net/encodings/p50k_base.tiktoken")
+	assert.Error(t, err)
+}
+
+func TestReadDataFromDir(t *testing.T) {
+	dir := useDataDir(t)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cl100k_base.tiktoken"), []byte("YQ== 0\n"), 0o644))
+	ranks, err := new(BpeLoader).LoadTiktokenBpe("cl100k_base.tiktoken")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 0}, ranks)
+
+	// files missing from the directory are read from the embedded ones
+	ranks, err = new(BpeLoader).LoadTiktokenBpe("o200k_base.tiktoken")
+	require.NoError(t, err)
+	assert.Greater(t, len(ranks), 199000)
+}